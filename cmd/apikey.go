@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/config"
+	"github.com/shenikar/Name-analyzer/internal/auth"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+)
+
+const apiKeyUsage = `usage:
+  name-analyzer apikey create -name NAME -scopes persons:read,persons:write [-tenant TENANT]
+  name-analyzer apikey list
+  name-analyzer apikey revoke -id ID
+
+scopes: persons:read, persons:write, metrics:read, admin`
+
+// runAPIKeyCommand создает, выводит и отзывает ключи API
+func runAPIKeyCommand(cfg *config.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+	if err := db.RunMigrations(cfg.DBDSN, logger); err != nil {
+		return err
+	}
+	database, err := db.ConnDB(cfg.DBDSN, logger)
+	if err != nil {
+		return err
+	}
+	defer database.Conn.Close()
+	ctx := context.Background()
+
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "create":
+		name := fs.String("name", "", "название ключа, например имя клиента")
+		scopes := fs.String("scopes", auth.ScopePersonsRead, "области доступа через запятую")
+		tenantID := fs.String("tenant", "", "арендатор, к данным которого дает доступ ключ")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *tenantID != "" && !tenant.Valid(*tenantID) {
+			return errors.New("-tenant must be 1-64 lowercase letters, digits, '_' or '-'")
+		}
+		if strings.TrimSpace(*name) == "" {
+			return errors.New("-name is required")
+		}
+		parsed, err := auth.ParseScopes(*scopes)
+		if err != nil {
+			return err
+		}
+		secret, hash, prefix, err := auth.GenerateKey()
+		if err != nil {
+			return err
+		}
+		key := &model.APIKey{Name: strings.TrimSpace(*name), Prefix: prefix, Scopes: parsed}
+		if *tenantID != "" {
+			key.Tenant = tenantID
+		}
+		if err := database.CreateAPIKey(ctx, key, hash); err != nil {
+			return err
+		}
+		fmt.Printf("id:     %s\nscopes: %s\ntenant: %s\nkey:    %s\n", key.ID, strings.Join(key.Scopes, ","), formatTenant(key.Tenant), secret)
+		fmt.Fprintln(os.Stderr, "Сохраните ключ: он больше не будет показан")
+		return nil
+
+	case "list":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		keys, err := database.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPES\tTENANT\tCREATED\tLAST USED\tREVOKED")
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%s\t%s\t%s...\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","),
+				formatTenant(k.Tenant), k.CreatedAt.Format("2006-01-02 15:04"), formatTime(k.LastUsedAt), formatTime(k.RevokedAt))
+		}
+		return tw.Flush()
+
+	case "revoke":
+		id := fs.String("id", "", "ID ключа")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		keyID, err := uuid.Parse(*id)
+		if err != nil {
+			return fmt.Errorf("invalid -id: %w", err)
+		}
+		if err := database.RevokeAPIKey(ctx, keyID); err == db.ErrNotFound {
+			return errors.New("key not found or already revoked")
+		} else if err != nil {
+			return err
+		}
+		fmt.Printf("key %s revoked\n", keyID)
+		return nil
+
+	default:
+		return errors.New(apiKeyUsage)
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// formatTenant выводит арендатора ключа; "-" - ключ не привязан к арендатору
+func formatTenant(t *string) string {
+	if t == nil {
+		return "-"
+	}
+	return *t
+}
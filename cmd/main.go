@@ -1,14 +1,27 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/shenikar/Name-analyzer/config"
 	"github.com/shenikar/Name-analyzer/internal/api"
+	"github.com/shenikar/Name-analyzer/internal/auth"
 	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/enrich"
+	"github.com/shenikar/Name-analyzer/internal/importer"
+	"github.com/shenikar/Name-analyzer/internal/logging"
+	"github.com/shenikar/Name-analyzer/internal/metrics"
+	"github.com/shenikar/Name-analyzer/internal/tracing"
+	"go.opentelemetry.io/otel"
 )
 
 // @title Name Analyzer API
@@ -24,42 +37,218 @@ import (
 // @license.name Apache 2.0
 // @license.url http://www.apache.org/licenses/LICENSE-2.0.html
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Ключ API ("Bearer na_...") или JWT ("Bearer eyJ...")
+
+// shutdownTimeout - сколько ждать завершения текущих запросов при остановке
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	if err := godotenv.Load(); err != nil {
-		log.Fatalf("failed to load .env file: %v", err)
+		fatal(slog.Default(), "failed to load .env file", err)
 	}
 
 	// Создаем конфигурацию приложения из переменных окружения
 	cfg, err := config.NewConfig()
 	if err != nil {
-		log.Fatalf("не удалось создать конфигурацию: %v", err)
+		fatal(slog.Default(), "не удалось создать конфигурацию", err)
 	}
 
-	// Инициализируем логгер с выводом номеров строк в логах
-	logger := log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile)
+	// Структурированный журнал с уровнем LOG_LEVEL в формате LOG_FORMAT
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fatal(slog.Default(), "не удалось настроить журнал", err)
+	}
+	slog.SetDefault(logger)
+
+	// Управление ключами API: name-analyzer apikey create|list|revoke
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := runAPIKeyCommand(cfg, logger, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "apikey: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	// Квоты арендаторов: name-analyzer tenant quota|list
+	if len(os.Args) > 1 && os.Args[1] == "tenant" {
+		if err := runTenantCommand(cfg, logger, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "tenant: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Трассировка запросов, обращений к провайдерам и запросов к БД
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		fatal(logger, "не удалось настроить трассировку", err)
+	}
 
 	// Применяем миграции к базе данных
-	if err := db.RunMigrations(cfg.DBDSN); err != nil {
-		log.Fatalf("не удалось применить миграции: %v", err)
+	if err := db.RunMigrations(cfg.DBDSN, logger); err != nil {
+		fatal(logger, "не удалось применить миграции", err)
+	}
+	// Ожидаемая версия схемы - старшая миграция, поставляемая с сервисом
+	schemaVersion, err := db.LatestMigration()
+	if err != nil {
+		fatal(logger, "не удалось определить версию схемы", err)
 	}
 
 	// Устанавливаем соединение с базой данных
-	db, err := db.ConnDB(cfg.DBDSN)
+	db, err := db.ConnDB(cfg.DBDSN, logger)
 	if err != nil {
-		log.Fatalf("не удалось подключиться к БД: %v", err)
+		fatal(logger, "не удалось подключиться к БД", err)
+	}
+
+	// Заполняем фонетические ключи для записей, созданных до появления поиска
+	if n, err := db.BackfillPhonetic(context.Background()); err != nil {
+		fatal(logger, "не удалось заполнить фонетические ключи", err)
+	} else if n > 0 {
+		logger.Info("phonetic keys backfilled", "persons", n)
+	}
+
+	// Изоляция арендаторов политиками RLS дополняет фильтрацию по tenant_id в запросах
+	if err := db.SetRowLevelSecurity(context.Background(), cfg.TenantRLS); err != nil {
+		fatal(logger, "не удалось настроить RLS", err)
 	}
+	db.DailyEnrichmentQuota = cfg.EnrichDailyQuota
+	db.SchemaVersion = schemaVersion
+
+	// Периодически удаляем просроченные ключи идемпотентности и неиспользуемые ведра лимитов
+	go func() {
+		for range time.Tick(time.Hour) {
+			if _, err := db.PurgeIdempotencyKeys(context.Background()); err != nil {
+				logger.Error("failed to purge idempotency keys", "error", err)
+			}
+			if cfg.RateLimitStore == "postgres" {
+				if _, err := db.PurgeRateLimits(context.Background(), time.Hour); err != nil {
+					logger.Error("failed to purge rate limits", "error", err)
+				}
+			}
+		}
+	}()
+
+	// Метрики пула соединений и количества записей арендаторов
+	metrics.RegisterDBStats(db.Conn.DB)
+	go func() {
+		for {
+			if counts, err := db.CountPersonsByTenant(context.Background()); err != nil {
+				logger.Error("failed to count persons", "error", err)
+			} else {
+				metrics.SetPersons(counts)
+			}
+			time.Sleep(time.Minute)
+		}
+	}()
+
+	// Политика доступа: какие операции и поля доступны ролям
+	policy := auth.DefaultPolicy()
+	if cfg.RBACPolicy != "" {
+		policy, err = auth.LoadPolicy(cfg.RBACPolicy)
+		if err != nil {
+			fatal(logger, "не удалось загрузить политику доступа", err)
+		}
+	}
+
+	enricher := enrich.New(logger)
+
+	// Фоновые импорты прерываются при остановке сервиса; задачи, прерванные прошлой остановкой, завершаются ошибкой
+	if n, err := db.FailInterruptedImports(context.Background()); err != nil {
+		fatal(logger, "не удалось завершить прерванные импорты", err)
+	} else if n > 0 {
+		logger.Warn("interrupted imports marked as failed", "imports", n)
+	}
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	imports := importer.New(jobsCtx, db, enricher, logger)
 
 	// Создаем новый роутер
 	mux := http.NewServeMux()
 	// Регистрируем все API маршруты
-	api.RegisterRoutes(mux, db, logger)
+	api.RegisterRoutes(mux, db, enricher, imports, cfg, policy, logger)
 
-	// Добавляем промежуточное ПО (middleware) для логирования и обработки паник
-	handler := api.LoggingMiddleware(logger)(api.RecoverMiddleware(mux))
+	// Ограничиваем частоту запросов; лимиты в Postgres общие для всех экземпляров сервиса
+	var handler http.Handler = mux
+	var limiter api.RateLimiter = api.NewMemoryRateLimiter()
+	if cfg.RateLimitStore == "postgres" {
+		limiter = db
+	}
+	if cfg.RateLimit > 0 {
+		limit := api.Limit{Rate: float64(cfg.RateLimit), Burst: cfg.RateLimitBurst}
+		handler = api.RateLimitMiddleware(limiter, limit, mux, logger)(handler)
+	}
+
+	// Проверка JWT, выпущенных шлюзом, по ключам JWKS
+	var verifier *auth.Verifier
+	if cfg.JWKS != "" {
+		keys, err := auth.NewJWKS(context.Background(), cfg.JWKS)
+		if err != nil {
+			fatal(logger, "не удалось загрузить JWKS", err)
+		}
+		roleScopes, err := auth.ParseRoleScopes(cfg.JWTRoleScopes)
+		if err != nil {
+			fatal(logger, "некорректный JWT_ROLE_SCOPES", err)
+		}
+		verifier = &auth.Verifier{
+			Keys:        keys,
+			Issuer:      cfg.JWTIssuer,
+			Audience:    cfg.JWTAudience,
+			RolesClaim:  cfg.JWTRolesClaim,
+			RoleScopes:  roleScopes,
+			TenantClaim: cfg.JWTTenantClaim,
+			Leeway:      time.Minute,
+		}
+	}
+
+	// Арендатор определяется после аутентификации
+	handler = api.TenantMiddleware()(handler)
+	handler = api.AuthMiddleware(db, verifier, logger)(handler)
+	// Лимит по IP-адресу проверяется до аутентификации и ограничивает и запросы с неверными учетными данными
+	if cfg.RateLimitIP > 0 {
+		limit := api.Limit{Rate: float64(cfg.RateLimitIP), Burst: 2 * cfg.RateLimitIP}
+		handler = api.IPRateLimitMiddleware(limiter, limit, mux, logger)(handler)
+	}
+	// Метрики учитывают и запросы, отклоненные аутентификацией и лимитами
+	handler = api.MetricsMiddleware(mux)(handler)
+	// Логирование и обработка паник охватывают все внутренние слои, в журнал попадают и отклоненные запросы
+	handler = api.LoggingMiddleware(logger)(api.RecoverMiddleware(logger)(handler))
+	handler = api.TracingMiddleware(otel.GetTracerProvider(), mux)(handler)
+	// Идентификатор запроса присваивается первым, чтобы попасть во все записи журнала и ответы об ошибках
+	handler = api.RequestIDMiddleware()(handler)
 
 	// Запускаем HTTP сервер на указанном порту
-	logger.Printf("Server is running on port: %s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, handler); err != nil {
-		logger.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: handler}
+	go func() {
+		logger.Info("server is running", "port", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal(logger, "failed to start server", err)
+		}
+	}()
+
+	// По сигналу завершаем обработку текущих запросов и отправляем накопленные спаны
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shut down server", "error", err)
+	}
+	cancelJobs()
+	imports.Wait()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", "error", err)
 	}
 }
+
+// fatal записывает ошибку в журнал и завершает процесс
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/shenikar/Name-analyzer/config"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+)
+
+const tenantUsage = `usage:
+  name-analyzer tenant quota -id TENANT -daily N     суточная квота обогащений арендатора
+  name-analyzer tenant quota -id TENANT -unlimited   снять ограничение для арендатора
+  name-analyzer tenant quota -id TENANT -default     вернуть квоту по умолчанию (ENRICH_DAILY_QUOTA)
+  name-analyzer tenant list                          квоты и обогащения за текущие сутки (UTC)`
+
+// runTenantCommand задает и выводит суточные квоты обогащений арендаторов
+func runTenantCommand(cfg *config.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(tenantUsage)
+	}
+	if err := db.RunMigrations(cfg.DBDSN, logger); err != nil {
+		return err
+	}
+	database, err := db.ConnDB(cfg.DBDSN, logger)
+	if err != nil {
+		return err
+	}
+	defer database.Conn.Close()
+	database.DailyEnrichmentQuota = cfg.EnrichDailyQuota
+	ctx := context.Background()
+
+	fs := flag.NewFlagSet("tenant "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "quota":
+		id := fs.String("id", "", "идентификатор арендатора")
+		daily := fs.Int("daily", -1, "суточная квота обогащений")
+		unlimited := fs.Bool("unlimited", false, "без ограничения")
+		reset := fs.Bool("default", false, "квота по умолчанию")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if !tenant.Valid(*id) {
+			return errors.New("-id must be 1-64 lowercase letters, digits, '_' or '-'")
+		}
+		switch {
+		case *reset:
+			if err := database.ResetTenantQuota(ctx, *id); err == db.ErrNotFound {
+				return errors.New("tenant has no custom quota")
+			} else if err != nil {
+				return err
+			}
+			fmt.Printf("tenant %s uses the default quota\n", *id)
+		case *unlimited:
+			if err := database.SetTenantQuota(ctx, *id, nil); err != nil {
+				return err
+			}
+			fmt.Printf("tenant %s: unlimited\n", *id)
+		case *daily >= 0:
+			if err := database.SetTenantQuota(ctx, *id, daily); err != nil {
+				return err
+			}
+			fmt.Printf("tenant %s: %d enrichments per day\n", *id, *daily)
+		default:
+			return errors.New(tenantUsage)
+		}
+		return nil
+
+	case "list":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		quotas, err := database.TenantQuotas(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TENANT\tDAILY QUOTA\tUSED TODAY")
+		for _, q := range quotas {
+			limit := "unlimited"
+			if q.Daily != nil {
+				limit = strconv.Itoa(*q.Daily)
+			}
+			if !q.Custom {
+				limit += " (default)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", q.Tenant, limit, q.Used)
+		}
+		return tw.Flush()
+
+	default:
+		return errors.New(tenantUsage)
+	}
+}
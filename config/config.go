@@ -1,15 +1,50 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"time"
 )
 
+// defaultIdempotencyTTL - срок хранения ответов для Idempotency-Key по умолчанию
+const defaultIdempotencyTTL = 24 * time.Hour
+
 type Config struct {
-	DBDSN     string
-	Port      string
-	LogLevel  string
+	DBDSN    string
+	Port     string
+	LogLevel string
+	// LogFormat - формат журнала: json (по умолчанию) или text
+	LogFormat string
+	// RateLimit - запросов в секунду на клиента, 0 отключает ограничение
 	RateLimit int
+	// RateLimitBurst - сколько запросов клиент может сделать подряд, по умолчанию 2*RateLimit
+	RateLimitBurst int
+	// RateLimitStore - memory (по умолчанию) или postgres для общих лимитов нескольких экземпляров
+	RateLimitStore string
+	// RateLimitIP - запросов в секунду с одного IP-адреса до аутентификации, по умолчанию 10*RateLimit;
+	// 0 отключает ограничение
+	RateLimitIP int
+	// IdempotencyTTL - сколько хранится ответ на запрос с Idempotency-Key
+	IdempotencyTTL time.Duration
+	// JWKS - путь к файлу или URL набора ключей для проверки JWT; пустое значение отключает JWT
+	JWKS        string
+	JWTIssuer   string
+	JWTAudience string
+	// JWTRolesClaim - утверждение с ролями, по умолчанию roles
+	JWTRolesClaim string
+	// JWTRoleScopes - сопоставление ролей областям: reader=persons:read;editor=persons:read,persons:write
+	JWTRoleScopes string
+	// JWTTenantClaim - утверждение с арендатором, по умолчанию tenant
+	JWTTenantClaim string
+	// RBACPolicy - путь к JSON-файлу политики доступа; пустое значение включает политику по умолчанию
+	RBACPolicy string
+	// TenantRLS включает политики RLS Postgres для изоляции арендаторов
+	TenantRLS bool
+	// EnrichDailyQuota - суточная квота обогащений арендатора по умолчанию, 0 - без ограничения
+	EnrichDailyQuota int
+	// TracesExporter - экспортер спанов OpenTelemetry: none (по умолчанию), otlp или stdout
+	TracesExporter string
 }
 
 func NewConfig() (*Config, error) {
@@ -18,11 +53,80 @@ func NewConfig() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if rateLimit < 0 {
+		return nil, fmt.Errorf("invalid RATE_LIMIT %d: must not be negative", rateLimit)
+	}
+	rateLimitBurst := 2 * rateLimit
+	if v := os.Getenv("RATE_LIMIT_BURST"); v != "" {
+		rateLimitBurst, err = strconv.Atoi(v)
+		if err != nil || rateLimitBurst < 1 {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_BURST %q: must be a positive integer", v)
+		}
+	}
+	rateLimitIP := 10 * rateLimit
+	if v := os.Getenv("RATE_LIMIT_IP"); v != "" {
+		rateLimitIP, err = strconv.Atoi(v)
+		if err != nil || rateLimitIP < 0 {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_IP %q: must be a non-negative integer", v)
+		}
+	}
+	rateLimitStore := os.Getenv("RATE_LIMIT_STORE")
+	switch rateLimitStore {
+	case "":
+		rateLimitStore = "memory"
+	case "memory", "postgres":
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_STORE %q: must be memory or postgres", rateLimitStore)
+	}
+	idempotencyTTL := defaultIdempotencyTTL
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		idempotencyTTL, err = time.ParseDuration(v)
+		if err != nil || idempotencyTTL <= 0 {
+			return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL %q: must be a positive duration", v)
+		}
+	}
+	var tenantRLS bool
+	if v := os.Getenv("TENANT_RLS"); v != "" {
+		tenantRLS, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TENANT_RLS %q: must be true or false", v)
+		}
+	}
+	var enrichDailyQuota int
+	if v := os.Getenv("ENRICH_DAILY_QUOTA"); v != "" {
+		enrichDailyQuota, err = strconv.Atoi(v)
+		if err != nil || enrichDailyQuota < 0 {
+			return nil, fmt.Errorf("invalid ENRICH_DAILY_QUOTA %q: must be a non-negative integer", v)
+		}
+	}
+	tracesExporter := os.Getenv("OTEL_TRACES_EXPORTER")
+	switch tracesExporter {
+	case "":
+		tracesExporter = "none"
+	case "none", "otlp", "stdout":
+	default:
+		return nil, fmt.Errorf("invalid OTEL_TRACES_EXPORTER %q: must be none, otlp or stdout", tracesExporter)
+	}
 	return &Config{
-		DBDSN:     os.Getenv("DB_DSN"),
-		Port:      os.Getenv("PORT"),
-		LogLevel:  os.Getenv("LOG_LEVEL"),
-		RateLimit: rateLimit,
+		DBDSN:            os.Getenv("DB_DSN"),
+		Port:             os.Getenv("PORT"),
+		LogLevel:         os.Getenv("LOG_LEVEL"),
+		LogFormat:        os.Getenv("LOG_FORMAT"),
+		RateLimit:        rateLimit,
+		RateLimitBurst:   rateLimitBurst,
+		RateLimitStore:   rateLimitStore,
+		RateLimitIP:      rateLimitIP,
+		IdempotencyTTL:   idempotencyTTL,
+		JWKS:             os.Getenv("JWT_JWKS"),
+		JWTIssuer:        os.Getenv("JWT_ISSUER"),
+		JWTAudience:      os.Getenv("JWT_AUDIENCE"),
+		JWTRolesClaim:    os.Getenv("JWT_ROLES_CLAIM"),
+		JWTRoleScopes:    os.Getenv("JWT_ROLE_SCOPES"),
+		RBACPolicy:       os.Getenv("RBAC_POLICY"),
+		JWTTenantClaim:   os.Getenv("JWT_TENANT_CLAIM"),
+		TenantRLS:        tenantRLS,
+		EnrichDailyQuota: enrichDailyQuota,
+		TracesExporter:   tracesExporter,
 	}, nil
 
 }
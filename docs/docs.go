@@ -22,152 +22,156 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/persons": {
-            "get": {
-                "description": "Возвращает список людей с возможностью фильтрации",
+        "/imports": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Принимает файл CSV (с заголовком) или NDJSON и запускает задачу импорта: строки читаются потоково,\nпроверяются, обогащаются и записываются пачками. Прогресс доступен по GET /imports/{id},\nошибочные строки - по GET /imports/{id}/errors.",
                 "consumes": [
-                    "application/json"
+                    "text/csv",
+                    "application/x-ndjson"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "persons"
+                    "imports"
                 ],
-                "summary": "Получить список людей",
+                "summary": "Импортировать людей из файла",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Фильтр по имени",
-                        "name": "name",
-                        "in": "query"
+                        "description": "Содержимое файла",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
                         "type": "string",
-                        "description": "Фильтр по фамилии",
-                        "name": "surname",
+                        "description": "Формат файла, по умолчанию определяется по Content-Type",
+                        "name": "format",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Фильтр по полу",
-                        "name": "gender",
+                        "description": "Сопоставление полей колонкам (ключам NDJSON): name:Имя,surname:Фамилия,patronymic:Отчество",
+                        "name": "mapping",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Фильтр по национальности",
-                        "name": "nationality",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Минимальный возраст",
-                        "name": "age_min",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Максимальный возраст",
-                        "name": "age_max",
+                        "default": ",",
+                        "description": "Разделитель колонок CSV",
+                        "name": "delimiter",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Количество записей на странице",
-                        "name": "limit",
+                        "type": "boolean",
+                        "default": true,
+                        "description": "Обогащать данные через внешние API",
+                        "name": "enrich",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Смещение",
-                        "name": "offset",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Import"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес задачи импорта"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "400": {
+                        "description": "Некорректный запрос",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
-                    }
-                }
-            },
-            "post": {
-                "description": "Создает новую запись и обогащает её данными о возрасте, поле и национальности",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "persons"
-                ],
-                "summary": "Создать новую запись о человеке",
-                "parameters": [
-                    {
-                        "description": "Данные о человеке",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.PersonRequest"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     },
-                    "400": {
-                        "description": "Некорректный запрос",
+                    "409": {
+                        "description": "Запрос с этим Idempotency-Key еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл слишком большой",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key уже использован с другим запросом",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     }
                 }
             }
         },
-        "/persons/{id}": {
+        "/imports/{id}": {
             "get": {
-                "description": "Возвращает детальную информацию о человеке",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Возвращает статус, прогресс и количество ошибочных строк задачи импорта",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "persons"
+                    "imports"
                 ],
-                "summary": "Получить информацию о человеке по ID",
+                "summary": "Получить состояние задачи импорта",
                 "parameters": [
                     {
                         "type": "string",
                         "format": "uuid",
-                        "description": "ID человека",
+                        "description": "ID задачи импорта",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -177,142 +181,1597 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Import"
                         }
                     },
                     "400": {
                         "description": "Некорректный ID",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     },
                     "404": {
-                        "description": "Человек не найден",
+                        "description": "Задача не найдена",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Обновляет существующую запись о человеке",
-                "consumes": [
-                    "application/json"
+            }
+        },
+        "/imports/{id}/errors": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Возвращает CSV с номером строки файла, причиной ошибки и исходным содержимым строки",
                 "produces": [
-                    "application/json"
+                    "text/csv"
                 ],
                 "tags": [
-                    "persons"
+                    "imports"
                 ],
-                "summary": "Обновить информацию о человеке",
+                "summary": "Скачать ошибочные строки импорта",
                 "parameters": [
                     {
                         "type": "string",
                         "format": "uuid",
-                        "description": "ID человека",
+                        "description": "ID задачи импорта",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Обновленные данные",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.PersonRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "CSV с колонками row_number, error, raw",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                            "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Некорректный запрос",
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     },
                     "404": {
-                        "description": "Человек не найден",
+                        "description": "Задача не найдена",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Удаляет запись о человеке по ID",
+            }
+        },
+        "/persons": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает список людей с возможностью фильтрации.\nПо умолчанию возвращается массив; с параметром envelope=true или заголовком\nAccept: application/vnd.name-analyzer.page+json возвращается конверт с общим количеством и ссылками.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/vnd.name-analyzer.page+json"
                 ],
                 "tags": [
                     "persons"
                 ],
-                "summary": "Удалить запись о человеке",
+                "summary": "Получить список людей",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "uuid",
-                        "description": "ID человека",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Фильтр по имени",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по фамилии",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по полу",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по национальности",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Нечеткий и фонетический поиск по имени и фамилии; результаты упорядочены по релевантности (score)",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age\u003e=30 and (gender=female or nationality in (RU,UA))",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Количество записей на странице",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Поля сортировки через запятую: name, surname, age, gender, nationality, created_at, updated_at; без sort - created_at по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "asc",
+                        "description": "Направления сортировки через запятую (asc|desc), одно на все поля или по одному на поле; требует sort",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор страницы из заголовков X-Next-Cursor/X-Prev-Cursor (несовместим с offset)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Вернуть конверт model.PersonList вместо массива",
+                        "name": "envelope",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "estimate"
+                        ],
+                        "type": "string",
+                        "default": "exact",
+                        "description": "Способ подсчета total в конверте",
+                        "name": "count",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Запись успешно удалена"
+                    "200": {
+                        "description": "Конверт при envelope=true",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.PersonList"
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Курсор следующей страницы"
+                            },
+                            "X-Prev-Cursor": {
+                                "type": "string",
+                                "description": "Курсор предыдущей страницы"
+                            }
+                        }
                     },
                     "400": {
-                        "description": "Некорректный ID",
+                        "description": "Некорректный фильтр, сортировка или курсор",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Человек не найден",
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.ErrorResponse"
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
                         }
                     }
                 }
-            }
-        }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает новую запись и обогащает её данными о возрасте, поле и национальности",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Создать новую запись о человеке",
+                "parameters": [
+                    {
+                        "description": "Данные о человеке",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.PersonRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или нарушения проверки полей",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Запрос с этим Idempotency-Key еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Слишком большое тело запроса",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type не application/json",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key уже использован с другим запросом",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Исчерпана суточная квота обогащений арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Потоково выгружает всех людей, подходящих под фильтры списка, в CSV, NDJSON или Parquet.\nС compress=gzip возвращается сжатый файл, иначе ответ сжимается при Accept-Encoding: gzip.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.apache.parquet"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Выгрузить людей в файл",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "parquet"
+                        ],
+                        "type": "string",
+                        "description": "Формат выгрузки",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "gzip"
+                        ],
+                        "type": "string",
+                        "description": "Сжать файл",
+                        "name": "compress",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по имени",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по фамилии",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по полу",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по национальности",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age\u003e=30 and (gender=female or nationality in (RU,UA))",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Нечеткий и фонетический поиск по имени и фамилии",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Поля сортировки через запятую; без sort - created_at по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "asc",
+                        "description": "Направления сортировки через запятую (asc|desc); требует sort",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл выгрузки",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный формат, фильтр или сортировка",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/merge": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сливает записи source_ids в target_id. Значения полей берутся из записей, указанных в fields,\nпо умолчанию - из целевой записи с заполнением пустых полей из источников.\nСнимки записей до слияния сохраняются в истории, источники удаляются.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Слить записи-дубли",
+                "parameters": [
+                    {
+                        "description": "Параметры слияния",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.MergeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Человек не найден",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Запрос с этим Idempotency-Key еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Слишком большое тело запроса",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type не application/json",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key уже использован с другим запросом",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает детальную информацию о человеке",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Получить информацию о человеке по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Человек не найден",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет существующую запись о человеке",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Обновить информацию о человеке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Обновленные данные",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.PersonRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или нарушения проверки полей",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Человек не найден",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Слишком большое тело запроса",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type не application/json",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет запись о человеке по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Удалить запись о человеке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Запись успешно удалена"
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Человек не найден",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Применяет к изменяемым полям записи (name, surname, patronymic, age, gender, nationality)\nJSON Merge Patch (RFC 7396) или JSON Patch (RFC 6902). В отличие от PUT, null или операция remove\nочищает поле. Имя и фамилия после применения патча обязательны.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Частично обновить запись о человеке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch, например {\\",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный патч или нарушения проверки полей в результате",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Человек не найден",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Операция test не выполнена",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Слишком большое тело запроса",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый Content-Type",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Операцию нельзя применить к записи",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/{id}/duplicates": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает записи, похожие на указанную по имени, фамилии и отчеству (точно, нечетко или фонетически) с учетом возраста и национальности",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Найти возможные дубли записи",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Максимальное количество кандидатов",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.DuplicateCandidate"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Человек не найден",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons:batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Проверяет каждую запись, обогащает их пакетными запросами к провайдерам и вставляет в одной транзакции.\nВ режиме atomic (по умолчанию) любая ошибка отменяет весь пакет, в режиме partial сохраняются успешные записи.\nВозвращает статус и ID для каждой записи.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Создать записи пакетом",
+                "parameters": [
+                    {
+                        "description": "Записи о людях",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.PersonRequest"
+                            }
+                        }
+                    },
+                    {
+                        "enum": [
+                            "atomic",
+                            "partial"
+                        ],
+                        "type": "string",
+                        "default": "atomic",
+                        "description": "Режим вставки",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Все записи созданы",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.BatchResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Часть записей создана (режим partial)",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные записи, ничего не создано; нарушения перечислены в items[].errors",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.BatchResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Запрос с этим Idempotency-Key еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Слишком большое тело запроса",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type не application/json",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key уже использован с другим запросом",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Исчерпана суточная квота обогащений арендатора",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает распределения по полу, национальности и возрастным интервалам, полноту обогащения\nи таблицу пола по национальностям для людей, подходящих под фильтры списка",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Получить статистику по людям",
+                "parameters": [
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Ширина возрастного интервала в годах",
+                        "name": "bucket_width",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по имени",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по фамилии",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по полу",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по национальности",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age\u003e=30 and (gender=female or nationality in (RU,UA))",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Нечеткий и фонетический поиск по имени и фамилии",
+                        "name": "q",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Stats"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный фильтр или ширина интервала",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/timeseries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает по интервалам количество созданных, измененных и удаленных записей\nи успешных и неудачных обращений к каждому провайдеру обогащения. Интервалы считаются в UTC,\nнеделя начинается с понедельника. Без from и to возвращаются последние 48 часов, 30 дней или 12 недель.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Получить динамику изменений",
+                "parameters": [
+                    {
+                        "enum": [
+                            "hour",
+                            "day",
+                            "week"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Интервал",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало диапазона в формате RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец диапазона в формате RFC 3339, не включается",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.TimeSeries"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный интервал или диапазон",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Нет ключа API или ключ недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "У ключа нет нужной области доступа",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Problem"
+                        }
+                    }
+                }
+            }
+        }
     },
     "definitions": {
-        "github_com_shenikar_Name-analyzer_internal_model.ErrorResponse": {
+        "github_com_shenikar_Name-analyzer_internal_model.AgeBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 27
+                },
+                "from": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "to": {
+                    "type": "integer",
+                    "example": 39
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "request contains invalid fields"
+                },
+                "errors": {
+                    "description": "Errors - нарушения проверки полей элемента",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.FieldViolation"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "39755c70-2ddb-4a62-90ea-1eeaf07a545a"
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "person": {
+                    "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 201
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer",
+                    "example": 9
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.BatchItemResult"
+                    }
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.Completeness": {
+            "type": "object",
+            "properties": {
+                "complete": {
+                    "description": "Complete - записи, у которых заполнены возраст, пол и национальность",
+                    "type": "integer",
+                    "example": 104
+                },
+                "empty": {
+                    "description": "Empty - записи без данных обогащения",
+                    "type": "integer",
+                    "example": 3
+                },
+                "with_age": {
+                    "type": "integer",
+                    "example": 110
+                },
+                "with_gender": {
+                    "type": "integer",
+                    "example": 115
+                },
+                "with_nationality": {
+                    "type": "integer",
+                    "example": 108
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.CrossTabCell": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "gender": {
+                    "type": "string",
+                    "example": "female"
+                },
+                "nationality": {
+                    "type": "string",
+                    "example": "RU"
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.DuplicateCandidate": {
             "type": "object",
             "properties": {
+                "person": {
+                    "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "surname_phonetic",
+                        "name_similar",
+                        "age_close"
+                    ]
+                },
+                "score": {
+                    "description": "Score - уверенность в том, что записи описывают одного человека, от 0 до 1",
+                    "type": "number",
+                    "example": 0.92
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.EnrichmentCounts": {
+            "type": "object",
+            "properties": {
+                "failure": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "success": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.FieldViolation": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "not_allowed"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "nationality"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be an ISO 3166-1 alpha-2 country code"
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.Import": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-03-20T15:04:05Z"
+                },
                 "error": {
+                    "type": "string"
+                },
+                "failed_rows": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "finished_at": {
+                    "type": "string",
+                    "example": "2024-03-20T15:05:10Z"
+                },
+                "format": {
+                    "type": "string",
+                    "example": "csv"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "8a0c1d3e-5f6a-4b7c-8d9e-0f1a2b3c4d5e"
+                },
+                "imported_rows": {
+                    "type": "integer",
+                    "example": 1480
+                },
+                "mapping": {
+                    "description": "Mapping сопоставляет поля записи (name, surname, patronymic) колонкам файла",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "description": "Status: pending, running, completed или failed",
+                    "type": "string",
+                    "example": "running"
+                },
+                "total_rows": {
+                    "type": "integer",
+                    "example": 1500
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-03-20T15:04:05Z"
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.MergeRequest": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "description": "Fields задает, из какой записи брать значение поля. По умолчанию берется\nзначение целевой записи, а если оно пустое - первое непустое из источников",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "source_ids": {
+                    "description": "SourceIDs - записи, которые вливаются в целевую и удаляются",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "target_id": {
+                    "description": "TargetID - запись, которая сохраняется после слияния",
+                    "type": "string",
+                    "example": "39755c70-2ddb-4a62-90ea-1eeaf07a545a"
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.PageLinks": {
+            "type": "object",
+            "properties": {
+                "next": {
+                    "type": "string",
+                    "example": "/api/v1/persons?limit=10\u0026offset=20"
+                },
+                "prev": {
+                    "type": "string",
+                    "example": "/api/v1/persons?limit=10\u0026offset=0"
+                },
+                "self": {
                     "type": "string",
-                    "example": "некорректный запрос"
+                    "example": "/api/v1/persons?limit=10\u0026offset=10"
                 }
             }
         },
@@ -347,6 +1806,11 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Иванович"
                 },
+                "score": {
+                    "description": "Score - релевантность записи, заполняется только при поиске",
+                    "type": "number",
+                    "example": 0.87
+                },
                 "surname": {
                     "type": "string",
                     "example": "Иванов"
@@ -357,6 +1821,45 @@ const docTemplate = `{
                 }
             }
         },
+        "github_com_shenikar_Name-analyzer_internal_model.PersonList": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Person"
+                    }
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "links": {
+                    "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.PageLinks"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "prev_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "total_estimated": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
         "github_com_shenikar_Name-analyzer_internal_model.PersonRequest": {
             "type": "object",
             "properties": {
@@ -385,6 +1888,153 @@ const docTemplate = `{
                     "example": "Иванов"
                 }
             }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "request contains invalid fields"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.FieldViolation"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/v1/persons"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "4f6c1b7e-2a0d-4c55-9a8e-1f2b3c4d5e6f"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Bad Request"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.Stats": {
+            "type": "object",
+            "properties": {
+                "by_age": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.AgeBucket"
+                    }
+                },
+                "by_gender": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.StatsBucket"
+                    }
+                },
+                "by_nationality": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.StatsBucket"
+                    }
+                },
+                "completeness": {
+                    "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.Completeness"
+                },
+                "gender_by_nationality": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.CrossTabCell"
+                    }
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 120
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.StatsBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 64
+                },
+                "key": {
+                    "type": "string",
+                    "example": "male"
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.TimeSeries": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string",
+                    "example": "2024-03-01T00:00:00Z"
+                },
+                "interval": {
+                    "type": "string",
+                    "example": "day"
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.TimeSeriesPoint"
+                    }
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2024-03-31T00:00:00Z"
+                }
+            }
+        },
+        "github_com_shenikar_Name-analyzer_internal_model.TimeSeriesPoint": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "2024-03-20T00:00:00Z"
+                },
+                "created": {
+                    "type": "integer",
+                    "example": 15
+                },
+                "deleted": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "enrichment": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/github_com_shenikar_Name-analyzer_internal_model.EnrichmentCounts"
+                    }
+                },
+                "updated": {
+                    "type": "integer",
+                    "example": 4
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "Ключ API (\"Bearer na_...\") или JWT (\"Bearer eyJ...\")",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
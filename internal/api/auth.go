@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/internal/auth"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// apiKeyTouchInterval - точность времени последнего использования ключа API
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyStore находит ключи API по хешу
+type APIKeyStore interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+}
+
+// AuthMiddleware аутентифицирует запрос по ключу API или JWT из Authorization: Bearer
+// (ключ API также из X-API-Key) и сохраняет субъекта в контексте. Запросы без учетных данных
+// пропускаются без субъекта, доступ к маршрутам проверяет RequirePermission.
+// Неизвестный или отозванный ключ и непрошедший проверку токен - 401.
+// Если verifier равен nil, JWT не принимаются.
+func AuthMiddleware(store APIKeyStore, verifier *auth.Verifier, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := credentials(r)
+			if !ok {
+				unauthorized(w, r, "authorization header must use the Bearer scheme")
+				return
+			}
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if auth.LooksLikeJWT(token) {
+				if verifier == nil {
+					unauthorized(w, r, "JWT authentication is not configured")
+					return
+				}
+				principal, err := verifier.Verify(r.Context(), token)
+				if err != nil {
+					logger.WarnContext(r.Context(), "authentication failed", "method", r.Method, "path", r.URL.Path,
+						"remote_addr", r.RemoteAddr, "error", err)
+					unauthorized(w, r, err.Error())
+					return
+				}
+				logPrincipal(r.Context(), principal)
+				next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+				return
+			}
+			key, err := store.GetAPIKeyByHash(r.Context(), auth.HashKey(token))
+			if err == db.ErrNotFound {
+				logger.WarnContext(r.Context(), "authentication failed", "method", r.Method, "path", r.URL.Path,
+					"remote_addr", r.RemoteAddr, "error", "unknown API key")
+				unauthorized(w, r, "invalid or revoked API key")
+				return
+			}
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to look up API key", "error", err)
+				internalError(w, r)
+				return
+			}
+			// Время использования обновляется не чаще apiKeyTouchInterval, чтобы не писать в базу на каждый запрос
+			if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) >= apiKeyTouchInterval {
+				if err := store.TouchAPIKey(r.Context(), key.ID); err != nil {
+					logger.WarnContext(r.Context(), "failed to update API key usage", "error", err)
+				}
+			}
+			principal := &auth.Principal{ID: key.ID.String(), Name: key.Name, Scopes: key.Scopes}
+			if key.Tenant != nil {
+				principal.Tenant = *key.Tenant
+			}
+			logPrincipal(r.Context(), principal)
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// credentials извлекает ключ из заголовков; ok равно false, если Authorization задан с другой схемой
+func credentials(r *http.Request) (token string, ok bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return "", false
+		}
+		return strings.TrimSpace(token), true
+	}
+	return r.Header.Get("X-API-Key"), true
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="name-analyzer"`)
+	writeProblem(w, r, model.Problem{Status: http.StatusUnauthorized, Detail: detail})
+}
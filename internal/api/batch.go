@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/shenikar/Name-analyzer/internal/enrich"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/validate"
+)
+
+// maxBatchSize ограничивает количество записей в одном пакетном запросе
+const maxBatchSize = 1000
+
+// CreatePersonsBatch godoc
+// @Summary Создать записи пакетом
+// @Description Проверяет каждую запись, обогащает их пакетными запросами к провайдерам и вставляет в одной транзакции.
+// @Description В режиме atomic (по умолчанию) любая ошибка отменяет весь пакет, в режиме partial сохраняются успешные записи.
+// @Description Возвращает статус и ID для каждой записи.
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param request body []model.PersonRequest true "Записи о людях"
+// @Param mode query string false "Режим вставки" Enums(atomic, partial) default(atomic)
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохраненный ответ"
+// @Success 201 {object} model.BatchResponse "Все записи созданы"
+// @Success 207 {object} model.BatchResponse "Часть записей создана (режим partial)"
+// @Failure 400 {object} model.BatchResponse "Некорректные записи, ничего не создано; нарушения перечислены в items[].errors"
+// @Failure 413 {object} model.Problem "Слишком большое тело запроса"
+// @Failure 415 {object} model.Problem "Content-Type не application/json"
+// @Failure 409 {object} model.Problem "Запрос с этим Idempotency-Key еще выполняется"
+// @Failure 422 {object} model.Problem "Idempotency-Key уже использован с другим запросом"
+// @Failure 429 {object} model.Problem "Исчерпана суточная квота обогащений арендатора"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /persons:batch [post]
+func (h *Handler) CreatePersonsBatch(w http.ResponseWriter, r *http.Request) {
+	partial := false
+	switch r.URL.Query().Get("mode") {
+	case "", "atomic":
+	case "partial":
+		partial = true
+	default:
+		badRequest(w, r, "mode must be atomic or partial")
+		return
+	}
+	var items []model.PersonRequest
+	if !decodeJSON(w, r, &items, maxBatchBodySize) {
+		return
+	}
+	if len(items) == 0 || len(items) > maxBatchSize {
+		badRequest(w, r, "batch must contain from 1 to 1000 items")
+		return
+	}
+
+	resp := model.BatchResponse{Items: make([]model.BatchItemResult, len(items))}
+	var (
+		valid   []int
+		names   []string
+		invalid bool
+	)
+	for i := range items {
+		item := &items[i]
+		resp.Items[i].Index = i
+		if violations := validate.PersonRequest(item, false); len(violations) > 0 {
+			resp.Items[i].Status = http.StatusBadRequest
+			resp.Items[i].Error = "request contains invalid fields"
+			resp.Items[i].Errors = violations
+			invalid = true
+			continue
+		}
+		valid = append(valid, i)
+		names = append(names, item.Name)
+	}
+	if invalid && !partial {
+		for _, i := range valid {
+			resp.Items[i].Status = http.StatusFailedDependency
+			resp.Items[i].Error = "not created: batch contains invalid items"
+		}
+		resp.Failed = len(items)
+		writeBatchResponse(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	if !h.consumeQuota(w, r, len(enrich.Unique(names))) {
+		return
+	}
+	enriched, outcomes := h.Enricher.EnrichBatch(r.Context(), names)
+	h.recordOutcomes(r.Context(), outcomes)
+	persons := make([]*model.Person, len(valid))
+	for j, i := range valid {
+		data := enriched[items[i].Name]
+		persons[j] = &model.Person{
+			Name:        items[i].Name,
+			Surname:     items[i].Surname,
+			Patronymic:  items[i].Patronymic,
+			Age:         data.Age,
+			Gender:      data.Gender,
+			Nationality: data.Nationality,
+		}
+	}
+	errs, err := h.DB.CreatePersons(r.Context(), persons, partial)
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to create persons batch", "error", err)
+		internalError(w, r)
+		return
+	}
+
+	for j, i := range valid {
+		if errs[j] != nil {
+			h.Logger.ErrorContext(r.Context(), "failed to create batch item", "index", i, "error", errs[j])
+			resp.Items[i].Status = http.StatusInternalServerError
+			resp.Items[i].Error = "db error"
+			continue
+		}
+		resp.Items[i].Status = http.StatusCreated
+		resp.Items[i].ID = &persons[j].ID
+		h.redact(r, persons[j])
+		resp.Items[i].Person = persons[j]
+		resp.Created++
+	}
+	resp.Failed = len(items) - resp.Created
+	status := http.StatusCreated
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	writeBatchResponse(w, status, resp)
+}
+
+func writeBatchResponse(w http.ResponseWriter, status int, resp model.BatchResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// Ограничения размера тела JSON-запросов
+const (
+	maxPersonBodySize = 16 << 10
+	maxMergeBodySize  = 64 << 10
+	maxBatchBodySize  = 4 << 20
+)
+
+var errTrailingData = errors.New("unexpected data after JSON value")
+
+// decodeJSON строго разбирает тело запроса в dst: требует Content-Type application/json,
+// ограничивает размер тела, отклоняет неизвестные поля и данные после JSON-значения.
+// При ошибке отправляет ответ в формате RFC 7807 и возвращает false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any, limit int64) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeProblem(w, r, model.Problem{
+			Status: http.StatusUnsupportedMediaType,
+			Detail: "content type must be application/json",
+		})
+		return false
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(dst)
+	if err == nil {
+		// После значения допустим только конец тела; dec.More не замечает лишних "}" и "]"
+		switch extra := dec.Decode(&struct{}{}); {
+		case errors.Is(extra, io.EOF):
+		case errors.As(extra, new(*http.MaxBytesError)):
+			err = extra
+		default:
+			err = errTrailingData
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	var (
+		maxErr    *http.MaxBytesError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	detail := "invalid request body"
+	switch {
+	case errors.As(err, &maxErr):
+		writeProblem(w, r, model.Problem{
+			Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit),
+		})
+		return false
+	case errors.Is(err, io.EOF):
+		detail = "request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		detail = "request body contains incomplete JSON"
+	case errors.As(err, &syntaxErr):
+		detail = fmt.Sprintf("request body contains malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		detail = fmt.Sprintf("field %q must be of type %s", typeErr.Field, typeErr.Type)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json не экспортирует тип ошибки для неизвестных полей
+		detail = "unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	case errors.Is(err, errTrailingData):
+		detail = err.Error()
+	}
+	badRequest(w, r, detail)
+	return false
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// FindDuplicates godoc
+// @Summary Найти возможные дубли записи
+// @Description Возвращает записи, похожие на указанную по имени, фамилии и отчеству (точно, нечетко или фонетически) с учетом возраста и национальности
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param id path string true "ID человека" format(uuid)
+// @Param limit query integer false "Максимальное количество кандидатов" default(10)
+// @Success 200 {array} model.DuplicateCandidate
+// @Failure 400 {object} model.Problem "Некорректный ID"
+// @Failure 404 {object} model.Problem "Человек не найден"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /persons/{id}/duplicates [get]
+func (h *Handler) FindDuplicates(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		badRequest(w, r, "invalid id")
+		return
+	}
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = min(n, 100)
+		}
+	}
+	person, err := h.DB.GetPerson(r.Context(), id)
+	if err == db.ErrNotFound {
+		notFound(w, r, "person not found")
+		return
+	}
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to get person", "error", err)
+		internalError(w, r)
+		return
+	}
+	candidates, err := h.DB.FindDuplicates(r.Context(), person, limit)
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to find duplicates", "error", err)
+		internalError(w, r)
+		return
+	}
+	if candidates == nil {
+		candidates = []*model.DuplicateCandidate{}
+	}
+	h.redactCandidates(r, candidates)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// MergePersons godoc
+// @Summary Слить записи-дубли
+// @Description Сливает записи source_ids в target_id. Значения полей берутся из записей, указанных в fields,
+// @Description по умолчанию - из целевой записи с заполнением пустых полей из источников.
+// @Description Снимки записей до слияния сохраняются в истории, источники удаляются.
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param request body model.MergeRequest true "Параметры слияния"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохраненный ответ"
+// @Success 200 {object} model.Person
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 404 {object} model.Problem "Человек не найден"
+// @Failure 413 {object} model.Problem "Слишком большое тело запроса"
+// @Failure 415 {object} model.Problem "Content-Type не application/json"
+// @Failure 409 {object} model.Problem "Запрос с этим Idempotency-Key еще выполняется"
+// @Failure 422 {object} model.Problem "Idempotency-Key уже использован с другим запросом"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /persons/merge [post]
+func (h *Handler) MergePersons(w http.ResponseWriter, r *http.Request) {
+	var mergeReq model.MergeRequest
+	if !decodeJSON(w, r, &mergeReq, maxMergeBodySize) {
+		return
+	}
+	person, err := h.DB.MergePersons(r.Context(), mergeReq.TargetID, mergeReq.SourceIDs, mergeReq.Fields)
+	if errors.Is(err, db.ErrInvalidMerge) {
+		badRequest(w, r, err.Error())
+		return
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		notFound(w, r, err.Error())
+		return
+	}
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to merge persons", "error", err)
+		internalError(w, r)
+		return
+	}
+	h.redact(r, person)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(person)
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/export"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// ExportPersons godoc
+// @Summary Выгрузить людей в файл
+// @Description Потоково выгружает всех людей, подходящих под фильтры списка, в CSV, NDJSON или Parquet.
+// @Description С compress=gzip возвращается сжатый файл, иначе ответ сжимается при Accept-Encoding: gzip.
+// @Tags persons
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.apache.parquet
+// @Param format query string true "Формат выгрузки" Enums(csv, ndjson, parquet)
+// @Param compress query string false "Сжать файл" Enums(gzip)
+// @Param name query string false "Фильтр по имени"
+// @Param surname query string false "Фильтр по фамилии"
+// @Param gender query string false "Фильтр по полу"
+// @Param nationality query string false "Фильтр по национальности"
+// @Param age_min query integer false "Минимальный возраст"
+// @Param age_max query integer false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age>=30 and (gender=female or nationality in (RU,UA))"
+// @Param q query string false "Нечеткий и фонетический поиск по имени и фамилии"
+// @Param sort query string false "Поля сортировки через запятую; без sort - created_at по убыванию"
+// @Param order query string false "Направления сортировки через запятую (asc|desc); требует sort" default(asc)
+// @Success 200 {file} file "Файл выгрузки"
+// @Failure 400 {object} model.Problem "Некорректный формат, фильтр или сортировка"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /persons/export [get]
+func (h *Handler) ExportPersons(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format != export.FormatCSV && format != export.FormatNDJSON && format != export.FormatParquet {
+		badRequest(w, r, "format must be csv, ndjson or parquet")
+		return
+	}
+	compress := q.Get("compress")
+	if compress != "" && compress != "gzip" {
+		badRequest(w, r, "compress must be gzip")
+		return
+	}
+	opts, err := parsePersonFilter(q)
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+	opts.Search = strings.TrimSpace(q.Get("q"))
+	opts.Sort, err = parseSort(q.Get("sort"), q.Get("order"))
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+	if denied := h.checkHiddenFilter(r, opts); len(denied) > 0 {
+		writeHiddenFilterProblem(w, r, denied)
+		return
+	}
+
+	filename := "persons." + format
+	var (
+		out io.Writer = w
+		gz  *gzip.Writer
+	)
+	switch {
+	case compress == "gzip":
+		filename += ".gz"
+		w.Header().Set("Content-Type", "application/gzip")
+		gz = gzip.NewWriter(w)
+	case acceptsGzip(r):
+		w.Header().Set("Content-Type", export.ContentType(format))
+		w.Header().Set("Content-Encoding", "gzip")
+		gz = gzip.NewWriter(w)
+	default:
+		w.Header().Set("Content-Type", export.ContentType(format))
+	}
+	if gz != nil {
+		out = gz
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// Заголовки отправляются вместе с первой записью, поэтому ошибки до нее
+	// (например, некорректная сортировка) еще можно вернуть клиенту
+	started := false
+	var writer export.Writer
+	err = h.DB.ExportPersons(r.Context(), opts, func(person *model.Person) error {
+		if !started {
+			started = true
+			var err error
+			if writer, err = export.NewWriter(format, out); err != nil {
+				return err
+			}
+		}
+		h.redact(r, person)
+		return writer.Write(person)
+	})
+	if err != nil && !started {
+		w.Header().Del("Content-Disposition")
+		w.Header().Del("Content-Encoding")
+		if errors.Is(err, db.ErrInvalidSort) {
+			badRequest(w, r, err.Error())
+			return
+		}
+		h.Logger.ErrorContext(r.Context(), "failed to export persons", "error", err)
+		internalError(w, r)
+		return
+	}
+	if err != nil {
+		// Часть файла уже отправлена: обрываем ответ, чтобы клиент не принял его за полный
+		h.Logger.ErrorContext(r.Context(), "failed to export persons", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	if !started {
+		if writer, err = export.NewWriter(format, out); err != nil {
+			h.Logger.ErrorContext(r.Context(), "failed to export persons", "error", err)
+			return
+		}
+	}
+	if err := writer.Close(); err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to finish export", "error", err)
+		return
+	}
+	if gz != nil {
+		gz.Close()
+	}
+}
+
+// acceptsGzip сообщает, принимает ли клиент ответы, сжатые gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(coding, "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
@@ -2,29 +2,31 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/internal/auth"
 	"github.com/shenikar/Name-analyzer/internal/db"
 	"github.com/shenikar/Name-analyzer/internal/enrich"
+	"github.com/shenikar/Name-analyzer/internal/importer"
 	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/validate"
 )
 
-type Handler struct {
-	DB     *db.DB
-	Logger *log.Logger
-}
+// maxListLimit ограничивает размер страницы списка людей
+const maxListLimit = 100
 
-var req struct {
-	Name        string  `json:"name"`
-	Surname     string  `json:"surname"`
-	Patronymic  *string `json:"patronymic"`
-	Age         *int    `json:"age"`
-	Gender      *string `json:"gender"`
-	Nationality *string `json:"nationality"`
+type Handler struct {
+	DB       *db.DB
+	Enricher *enrich.Enricher
+	Importer *importer.Importer
+	// Policy определяет скрытые от субъекта поля; nil - поля не скрываются
+	Policy *auth.Policy
+	Logger *slog.Logger
 }
 
 // CreatePerson godoc
@@ -34,23 +36,35 @@ var req struct {
 // @Accept json
 // @Produce json
 // @Param request body model.PersonRequest true "Данные о человеке"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохраненный ответ"
 // @Success 201 {object} model.Person
-// @Failure 400 {object} model.ErrorResponse "Некорректный запрос"
-// @Failure 500 {object} model.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 400 {object} model.Problem "Некорректный запрос или нарушения проверки полей"
+// @Failure 413 {object} model.Problem "Слишком большое тело запроса"
+// @Failure 415 {object} model.Problem "Content-Type не application/json"
+// @Failure 409 {object} model.Problem "Запрос с этим Idempotency-Key еще выполняется"
+// @Failure 422 {object} model.Problem "Idempotency-Key уже использован с другим запросом"
+// @Failure 429 {object} model.Problem "Исчерпана суточная квота обогащений арендатора"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /persons [post]
 func (h *Handler) CreatePerson(w http.ResponseWriter, r *http.Request) {
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	var req model.PersonRequest
+	if !decodeJSON(w, r, &req, maxPersonBodySize) {
+		return
+	}
+	if violations := validate.PersonRequest(&req, false); len(violations) > 0 {
+		writeValidationProblem(w, r, violations)
 		return
 	}
-	req.Name = strings.TrimSpace(req.Name)
-	req.Surname = strings.TrimSpace(req.Surname)
-	if req.Name == "" || req.Surname == "" {
-		http.Error(w, "name and surname are required", http.StatusBadRequest)
+	if !h.consumeQuota(w, r, 1) {
 		return
 	}
 	ctx := r.Context()
-	data, _ := enrich.EnrichPerson(ctx, req.Name)
+	data, _ := h.Enricher.EnrichPerson(ctx, req.Name)
+	h.recordOutcomes(ctx, data.Outcomes)
 
 	person := &model.Person{
 		Name:        req.Name,
@@ -61,10 +75,11 @@ func (h *Handler) CreatePerson(w http.ResponseWriter, r *http.Request) {
 		Nationality: data.Nationality,
 	}
 	if err := h.DB.CreatePerson(ctx, person); err != nil {
-		h.Logger.Printf("failed to create person: %v", err)
-		http.Error(w, "db error", http.StatusInternalServerError)
+		h.Logger.ErrorContext(r.Context(), "failed to create person", "error", err)
+		internalError(w, r)
 		return
 	}
+	h.redact(r, person)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(person)
@@ -78,85 +93,201 @@ func (h *Handler) CreatePerson(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "ID человека" format(uuid)
 // @Success 200 {object} model.Person
-// @Failure 400 {object} model.ErrorResponse "Некорректный ID"
-// @Failure 404 {object} model.ErrorResponse "Человек не найден"
-// @Failure 500 {object} model.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 400 {object} model.Problem "Некорректный ID"
+// @Failure 404 {object} model.Problem "Человек не найден"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /persons/{id} [get]
 func (h *Handler) GetPerson(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/persons/")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		badRequest(w, r, "invalid id")
 		return
 	}
 	person, err := h.DB.GetPerson(r.Context(), id)
 	if err == db.ErrNotFound {
-		http.Error(w, "person not found", http.StatusNotFound)
+		notFound(w, r, "person not found")
 		return
 	}
 	if err != nil {
-		h.Logger.Printf("failed to get person: %v", err)
-		http.Error(w, "db error", http.StatusInternalServerError)
+		h.Logger.ErrorContext(r.Context(), "failed to get person", "error", err)
+		internalError(w, r)
 		return
 	}
+	h.redact(r, person)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(person)
 }
 
 // ListPersons godoc
 // @Summary Получить список людей
-// @Description Возвращает список людей с возможностью фильтрации
+// @Description Возвращает список людей с возможностью фильтрации.
+// @Description По умолчанию возвращается массив; с параметром envelope=true или заголовком
+// @Description Accept: application/vnd.name-analyzer.page+json возвращается конверт с общим количеством и ссылками.
 // @Tags persons
 // @Accept json
 // @Produce json
+// @Produce application/vnd.name-analyzer.page+json
 // @Param name query string false "Фильтр по имени"
 // @Param surname query string false "Фильтр по фамилии"
 // @Param gender query string false "Фильтр по полу"
 // @Param nationality query string false "Фильтр по национальности"
 // @Param age_min query integer false "Минимальный возраст"
 // @Param age_max query integer false "Максимальный возраст"
-// @Param limit query integer false "Количество записей на странице" default(10)
-// @Param offset query integer false "Смещение" default(0)
+// @Param q query string false "Нечеткий и фонетический поиск по имени и фамилии; результаты упорядочены по релевантности (score)"
+// @Param filter query string false "Выражение фильтра, например: age>=30 and (gender=female or nationality in (RU,UA))"
+// @Param limit query integer false "Количество записей на странице" default(10) maximum(100)
+// @Param offset query integer false "Смещение" default(0) minimum(0)
+// @Param sort query string false "Поля сортировки через запятую: name, surname, age, gender, nationality, created_at, updated_at; без sort - created_at по убыванию"
+// @Param order query string false "Направления сортировки через запятую (asc|desc), одно на все поля или по одному на поле; требует sort" default(asc)
+// @Param cursor query string false "Курсор страницы из заголовков X-Next-Cursor/X-Prev-Cursor (несовместим с offset)"
+// @Param envelope query boolean false "Вернуть конверт model.PersonList вместо массива"
+// @Param count query string false "Способ подсчета total в конверте" Enums(exact, estimate) default(exact)
 // @Success 200 {array} model.Person
-// @Failure 500 {object} model.ErrorResponse "Внутренняя ошибка сервера"
+// @Success 200 {object} model.PersonList "Конверт при envelope=true"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы"
+// @Header 200 {string} X-Prev-Cursor "Курсор предыдущей страницы"
+// @Failure 400 {object} model.Problem "Некорректный фильтр, сортировка или курсор"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /persons [get]
 func (h *Handler) ListPersons(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
-	filter := map[string]interface{}{}
-	if v := q.Get("name"); v != "" {
-		filter["name"] = v
+	opts, err := parsePersonFilter(q)
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+	limit := 10
+	offset := 0
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			badRequest(w, r, "limit must be an integer")
+			return
+		}
 	}
-	if v := q.Get("surname"); v != "" {
-		filter["surnamename"] = v
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			badRequest(w, r, "offset must be a non-negative integer")
+			return
+		}
 	}
-	if v := q.Get("gender"); v != "" {
-		filter["gender"] = v
+	if limit <= 0 {
+		limit = 10
 	}
-	if v := q.Get("nationality"); v != "" {
-		filter["nationality"] = v
+	limit = min(limit, maxListLimit)
+	sort, err := parseSort(q.Get("sort"), q.Get("order"))
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
 	}
-	if v := q.Get("age_min"); v != "" {
-		filter["age_min"] = v
+	opts.Sort = sort
+	if denied := h.checkHiddenFilter(r, opts); len(denied) > 0 {
+		writeHiddenFilterProblem(w, r, denied)
+		return
 	}
-	if v := q.Get("age_max"); v != "" {
-		filter["age_max"] = v
+	opts.Limit = limit
+	opts.Offset = offset
+	if v := strings.TrimSpace(q.Get("q")); v != "" {
+		if q.Has("sort") || q.Has("cursor") {
+			badRequest(w, r, "search results are ordered by relevance and support only offset pagination")
+			return
+		}
+		opts.Search = v
 	}
-	limit := 10
-	offset := 0
-	if v := q.Get("limit"); v != "" {
-		fmt.Sscanf(v, "%d", &limit)
+	if v := q.Get("cursor"); v != "" {
+		if q.Has("offset") {
+			badRequest(w, r, "cursor and offset cannot be used together")
+			return
+		}
+		cursor, err := db.ParseCursor(v)
+		if err != nil {
+			badRequest(w, r, "invalid cursor")
+			return
+		}
+		opts.Cursor = cursor
 	}
-	if v := q.Get("offset"); v != "" {
-		fmt.Sscanf(v, "%d", &offset)
+	page, err := h.DB.ListPersons(r.Context(), opts)
+	if errors.Is(err, db.ErrInvalidSort) {
+		badRequest(w, r, err.Error())
+		return
+	}
+	if errors.Is(err, db.ErrInvalidCursor) {
+		badRequest(w, r, "cursor is invalid or does not match sort")
+		return
 	}
-	persons, err := h.DB.ListPersons(r.Context(), filter, limit, offset)
 	if err != nil {
-		h.Logger.Printf("error listing persons: %v", err)
-		http.Error(w, "db error", http.StatusInternalServerError)
+		h.Logger.ErrorContext(r.Context(), "error listing persons", "error", err)
+		internalError(w, r)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(persons)
+	if page.NextCursor != nil {
+		w.Header().Set("X-Next-Cursor", page.NextCursor.Encode())
+	}
+	if page.PrevCursor != nil {
+		w.Header().Set("X-Prev-Cursor", page.PrevCursor.Encode())
+	}
+	persons := page.Persons
+	if persons == nil {
+		persons = []*model.Person{}
+	}
+	h.redact(r, persons...)
+	contentType, envelope := envelopeMediaType(r)
+	if !envelope {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(persons)
+		return
+	}
+
+	estimate := q.Get("count") == "estimate"
+	total, err := h.DB.CountPersons(r.Context(), opts, estimate)
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "error counting persons", "error", err)
+		internalError(w, r)
+		return
+	}
+	list := model.PersonList{
+		Items:          persons,
+		Total:          total,
+		TotalEstimated: estimate,
+		Limit:          limit,
+		Links:          model.PageLinks{Self: pageLink(r, nil)},
+	}
+	if opts.Cursor != nil {
+		list.Cursor = q.Get("cursor")
+	} else {
+		list.Offset = &offset
+	}
+	if page.NextCursor != nil {
+		list.NextCursor = page.NextCursor.Encode()
+	}
+	if page.PrevCursor != nil {
+		list.PrevCursor = page.PrevCursor.Encode()
+	}
+	if opts.Cursor != nil {
+		if list.NextCursor != "" {
+			list.Links.Next = pageLink(r, map[string]string{"cursor": list.NextCursor})
+		}
+		if list.PrevCursor != "" {
+			list.Links.Prev = pageLink(r, map[string]string{"cursor": list.PrevCursor})
+		}
+	} else {
+		if page.HasNext {
+			list.Links.Next = pageLink(r, map[string]string{"offset": strconv.Itoa(offset + limit)})
+		}
+		if page.HasPrev {
+			list.Links.Prev = pageLink(r, map[string]string{"offset": strconv.Itoa(max(offset-limit, 0))})
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	json.NewEncoder(w).Encode(list)
 }
 
 // UpdatePerson godoc
@@ -168,29 +299,39 @@ func (h *Handler) ListPersons(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "ID человека" format(uuid)
 // @Param request body model.PersonRequest true "Обновленные данные"
 // @Success 200 {object} model.Person
-// @Failure 400 {object} model.ErrorResponse "Некорректный запрос"
-// @Failure 404 {object} model.ErrorResponse "Человек не найден"
-// @Failure 500 {object} model.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 400 {object} model.Problem "Некорректный запрос или нарушения проверки полей"
+// @Failure 404 {object} model.Problem "Человек не найден"
+// @Failure 413 {object} model.Problem "Слишком большое тело запроса"
+// @Failure 415 {object} model.Problem "Content-Type не application/json"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /persons/{id} [put]
 func (h *Handler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/persons/")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		badRequest(w, r, "invalid id")
 		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	var req model.PersonRequest
+	if !decodeJSON(w, r, &req, maxPersonBodySize) {
+		return
+	}
+	if violations := validate.PersonRequest(&req, true); len(violations) > 0 {
+		writeValidationProblem(w, r, violations)
 		return
 	}
 	person, err := h.DB.GetPerson(r.Context(), id)
 	if err == db.ErrNotFound {
-		http.Error(w, "person not found", http.StatusNotFound)
+		notFound(w, r, "person not found")
 		return
 	}
 	if err != nil {
-		h.Logger.Printf("failed to update person: %v", err)
-		http.Error(w, "db error", http.StatusInternalServerError)
+		h.Logger.ErrorContext(r.Context(), "failed to update person", "error", err)
+		internalError(w, r)
 		return
 	}
 	if req.Name != "" {
@@ -212,10 +353,11 @@ func (h *Handler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
 		person.Nationality = req.Nationality
 	}
 	if err := h.DB.UpdatePerson(r.Context(), person); err != nil {
-		h.Logger.Printf("failed to update person: %v", err)
-		http.Error(w, "db error", http.StatusInternalServerError)
+		h.Logger.ErrorContext(r.Context(), "failed to update person", "error", err)
+		internalError(w, r)
 		return
 	}
+	h.redact(r, person)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(person)
 }
@@ -228,25 +370,28 @@ func (h *Handler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "ID человека" format(uuid)
 // @Success 204 "Запись успешно удалена"
-// @Failure 400 {object} model.ErrorResponse "Некорректный ID"
-// @Failure 404 {object} model.ErrorResponse "Человек не найден"
-// @Failure 500 {object} model.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 400 {object} model.Problem "Некорректный ID"
+// @Failure 404 {object} model.Problem "Человек не найден"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /persons/{id} [delete]
 func (h *Handler) DeletePerson(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "api/v1/persons/")
-	id, err := uuid.Parse(idStr)
+	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		badRequest(w, r, "invalid id")
 		return
 	}
 	err = h.DB.DeletePerson(r.Context(), id)
 	if err == db.ErrNotFound {
-		http.Error(w, "person not found", http.StatusNotFound)
+		notFound(w, r, "person not found")
 		return
 	}
 	if err != nil {
-		h.Logger.Printf("failed to delete person: %v", err)
-		http.Error(w, "db error", http.StatusInternalServerError)
+		h.Logger.ErrorContext(r.Context(), "failed to delete person", "error", err)
+		internalError(w, r)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
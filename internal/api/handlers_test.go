@@ -0,0 +1,236 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/logging"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// Тесты рассчитаны на запуск с детектором гонок: go test -race ./internal/api
+
+func newTestHandler(database *db.DB) *Handler {
+	return &Handler{DB: database, Logger: logging.Discard()}
+}
+
+func doRequest(handler http.HandlerFunc, method, path, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) model.Problem {
+	t.Helper()
+	// Вызывается и из горутин, поэтому t.Errorf вместо t.Fatalf
+	var problem model.Problem
+	if ct := rec.Header().Get("Content-Type"); ct != problemMediaType {
+		t.Errorf("Content-Type = %q, want %q", ct, problemMediaType)
+		return problem
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Errorf("decode problem: %v", err)
+	}
+	return problem
+}
+
+func violatedFields(problem model.Problem) []string {
+	fields := make([]string, len(problem.Errors))
+	for i, v := range problem.Errors {
+		fields[i] = v.Field
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+func TestDecodeJSON(t *testing.T) {
+	h := newTestHandler(nil)
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		detail      string
+	}{
+		{"missing content type", "", `{"name":"Иван"}`, http.StatusUnsupportedMediaType, ""},
+		{"wrong content type", "text/plain", `{"name":"Иван"}`, http.StatusUnsupportedMediaType, ""},
+		{"empty body", "application/json", ``, http.StatusBadRequest, ""},
+		{"malformed", "application/json", `{"name":`, http.StatusBadRequest, ""},
+		{"unknown field", "application/json", `{"name":"Иван","surname":"Петров","id":"x"}`, http.StatusBadRequest, ""},
+		{"wrong type", "application/json", `{"name":"Иван","surname":"Петров","age":"30"}`, http.StatusBadRequest, ""},
+		{"trailing data", "application/json", `{"name":"Иван","surname":"Петров"} {}`, http.StatusBadRequest, "unexpected data after JSON value"},
+		{"trailing brace", "application/json", `{"name":"Иван","surname":"Петров"}}`, http.StatusBadRequest, "unexpected data after JSON value"},
+		{"trailing bracket", "application/json", `{"name":"Иван","surname":"Петров"}]`, http.StatusBadRequest, "unexpected data after JSON value"},
+		{"too large", "application/json", `{"name":"` + strings.Repeat("a", maxPersonBodySize) + `"}`, http.StatusRequestEntityTooLarge, ""},
+		{"charset parameter", "application/json; charset=utf-8", `{"name":"Иван"}`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h.CreatePerson, http.MethodPost, "/api/v1/persons", tt.contentType, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if problem := decodeProblem(t, rec); tt.detail != "" && problem.Detail != tt.detail {
+				t.Fatalf("detail = %q, want %q", problem.Detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestListPersonsInvalidPagination(t *testing.T) {
+	h := newTestHandler(nil)
+	for _, query := range []string{"limit=abc", "limit=1.5", "offset=abc", "offset=-1"} {
+		rec := doRequest(h.ListPersons, http.MethodGet, "/api/v1/persons?"+query, "", "")
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+		decodeProblem(t, rec)
+	}
+}
+
+func TestPatchPersonTooLarge(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PATCH /api/v1/persons/{id}", newTestHandler(nil).PatchPerson)
+	body := `{"name":"` + strings.Repeat("a", maxPatchSize) + `"}`
+	rec := doRequest(mux.ServeHTTP, http.MethodPatch, "/api/v1/persons/"+uuid.NewString(), "application/merge-patch+json", body)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusRequestEntityTooLarge, rec.Body)
+	}
+	decodeProblem(t, rec)
+}
+
+// TestCreatePersonConcurrent отправляет параллельно запросы с разными ошибками:
+// если состояние запроса разделяется между горутинами, нарушения одного запроса
+// попадут в ответ другого, а детектор гонок сообщит о конкурентной записи.
+func TestCreatePersonConcurrent(t *testing.T) {
+	h := newTestHandler(nil)
+	bodies := []struct {
+		body   string
+		fields []string
+	}{
+		{`{"name":"Иван","surname":"Петров","nationality":"ZZ"}`, []string{"nationality"}},
+		{`{"name":"Anna","surname":""}`, []string{"surname"}},
+		{`{"name":"Олег","surname":"Сидоров","age":200,"gender":"x"}`, []string{"age", "gender"}},
+		{`{"name":"1","surname":"Smith","patronymic":"?"}`, []string{"name", "patronymic"}},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		tc := bodies[i%len(bodies)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := doRequest(h.CreatePerson, http.MethodPost, "/api/v1/persons", "application/json", tc.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400: %s", rec.Code, rec.Body)
+				return
+			}
+			if got := violatedFields(decodeProblem(t, rec)); !slices.Equal(got, tc.fields) {
+				t.Errorf("body %s: violated fields = %v, want %v", tc.body, got, tc.fields)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestUpdatePersonConcurrentValidation(t *testing.T) {
+	h := newTestHandler(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		body, field := `{"gender":"unknown"}`, "gender"
+		if i%2 == 1 {
+			body, field = `{"surname":"`+strings.Repeat("я", 101)+`"}`, "surname"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := doRequest(h.UpdatePerson, http.MethodPut, "/api/v1/persons/"+uuid.NewString(), "application/json", body)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400: %s", rec.Code, rec.Body)
+				return
+			}
+			if got := violatedFields(decodeProblem(t, rec)); !slices.Equal(got, []string{field}) {
+				t.Errorf("violated fields = %v, want [%s]", got, field)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestUpdatePersonConcurrentDB параллельно обновляет разные записи и проверяет,
+// что каждый ответ и каждая запись содержат данные своего запроса.
+// Нужна база с примененными миграциями: TEST_DB_DSN=postgres://...
+func TestUpdatePersonConcurrentDB(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+	database, err := db.ConnDB(dsn, logging.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Conn.Close()
+	h := newTestHandler(database)
+	ctx := context.Background()
+
+	const n = 50
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		person := &model.Person{Name: "Тест", Surname: "Гонка"}
+		if err := database.CreatePerson(ctx, person); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = person.ID
+	}
+	t.Cleanup(func() {
+		for _, id := range ids {
+			database.DeletePerson(ctx, id)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for round := 0; round < 4; round++ {
+		for i, id := range ids {
+			surname := fmt.Sprintf("Гонка-%s", strings.Repeat("а", i+1))
+			body := fmt.Sprintf(`{"surname":%q}`, surname)
+			if i%2 == 0 {
+				body = fmt.Sprintf(`{"surname":%q,"patronymic":"Иванович"}`, surname)
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rec := doRequest(h.UpdatePerson, http.MethodPut, "/api/v1/persons/"+id.String(), "application/json", body)
+				if rec.Code != http.StatusOK {
+					t.Errorf("status = %d, want 200: %s", rec.Code, rec.Body)
+					return
+				}
+				var person model.Person
+				if err := json.NewDecoder(rec.Body).Decode(&person); err != nil {
+					t.Errorf("decode person: %v", err)
+					return
+				}
+				if person.ID != id || person.Surname != surname {
+					t.Errorf("person %s: got id %s surname %q, want %q", id, person.ID, person.Surname, surname)
+				}
+				if (person.Patronymic != nil) != (i%2 == 0) {
+					t.Errorf("person %s: patronymic leaked between requests", id)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/enrich"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// readinessTimeout ограничивает время проверок готовности
+const readinessTimeout = 2 * time.Second
+
+// probePaths - пути проверок оркестратора; успешные проверки пишутся в журнал на уровне debug
+var probePaths = map[string]bool{"/healthz": true, "/readyz": true}
+
+// Healthz сообщает, что процесс жив и обрабатывает запросы
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, model.HealthReport{Status: model.HealthOK})
+}
+
+// Readyz проверяет зависимости: доступность БД и версию схемы (критичные, при отказе - 503)
+// и провайдеров обогащения (некритичные: без них записи создаются без обогащения, статус degraded).
+// Провайдеры не опрашиваются: их состояние берется из автоматов отключения по последним обращениям.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	report := model.HealthReport{Status: model.HealthOK, Checks: map[string]model.HealthCheck{
+		"database":   h.checkDatabase(ctx),
+		"migrations": h.checkMigrations(ctx),
+	}}
+	for provider, status := range h.Enricher.Providers() {
+		report.Checks[provider] = providerCheck(status)
+	}
+	for _, check := range report.Checks {
+		switch {
+		case check.Status == model.HealthOK:
+		case check.Critical:
+			report.Status = model.HealthUnavailable
+		case report.Status == model.HealthOK:
+			report.Status = model.HealthDegraded
+		}
+	}
+	status := http.StatusOK
+	if report.Status == model.HealthUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, status, report)
+}
+
+func (h *Handler) checkDatabase(ctx context.Context) model.HealthCheck {
+	check := model.HealthCheck{Status: model.HealthOK, Critical: true}
+	if err := h.DB.Conn.PingContext(ctx); err != nil {
+		check.Status, check.Error = model.HealthFail, err.Error()
+	}
+	return check
+}
+
+// checkMigrations не пропускает незавершенную миграцию и схему старше ожидаемой сервисом;
+// более новая схема допустима, пока экземпляры обновляются по очереди
+func (h *Handler) checkMigrations(ctx context.Context) model.HealthCheck {
+	expected := h.DB.SchemaVersion
+	check := model.HealthCheck{Status: model.HealthOK, Critical: true, ExpectedVersion: &expected}
+	version, dirty, err := h.DB.MigrationVersion(ctx)
+	switch {
+	case err != nil:
+		check.Status, check.Error = model.HealthFail, err.Error()
+		return check
+	case dirty:
+		check.Status, check.Error = model.HealthFail, fmt.Sprintf("migration %d is dirty", version)
+	case version < expected:
+		check.Status, check.Error = model.HealthFail, fmt.Sprintf("schema version %d is older than expected %d", version, expected)
+	}
+	check.Version = &version
+	return check
+}
+
+// providerCheck считает провайдер недоступным, пока его автомат отключения не закрыт
+func providerCheck(status enrich.ProviderStatus) model.HealthCheck {
+	check := model.HealthCheck{
+		Status:              model.HealthOK,
+		Circuit:             status.Circuit,
+		ConsecutiveFailures: status.ConsecutiveFailures,
+	}
+	if status.Circuit != enrich.CircuitClosed {
+		check.Status, check.Error = model.HealthFail, status.LastError
+	}
+	if !status.LastSuccess.IsZero() {
+		check.LastSuccess = &status.LastSuccess
+	}
+	if !status.LastFailure.IsZero() {
+		check.LastFailure = &status.LastFailure
+	}
+	return check
+}
+
+func writeHealth(w http.ResponseWriter, status int, report model.HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/enrich"
+	"github.com/shenikar/Name-analyzer/internal/logging"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// fakeSchema - БД для проверок готовности: отвечает на ping и запрос версии из schema_migrations
+type fakeSchema struct {
+	// down - ошибка подключения, nil - БД доступна
+	down    error
+	version int64
+	dirty   bool
+}
+
+func (s *fakeSchema) Connect(context.Context) (driver.Conn, error) {
+	if s.down != nil {
+		return nil, s.down
+	}
+	return fakeConn{s}, nil
+}
+
+func (s *fakeSchema) Driver() driver.Driver { return nil }
+
+type fakeConn struct{ schema *fakeSchema }
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (fakeConn) Ping(context.Context) error          { return nil }
+
+func (c fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &versionRows{schema: c.schema}, nil
+}
+
+// versionRows - одна строка schema_migrations
+type versionRows struct {
+	schema *fakeSchema
+	done   bool
+}
+
+func (r *versionRows) Columns() []string { return []string{"version", "dirty"} }
+func (r *versionRows) Close() error      { return nil }
+
+func (r *versionRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0], dest[1] = r.schema.version, r.schema.dirty
+	return nil
+}
+
+// failProvider отключает genderize: автомат срабатывает после 5 ответов 503 подряд
+func failProvider(t *testing.T, e *enrich.Enricher) {
+	t.Helper()
+	e.HTTP = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: r}, nil
+	})}
+	for i := 0; i < 5; i++ {
+		e.GetGender(context.Background(), "Иван")
+	}
+	if e.Providers()[enrich.ProviderGenderize].Circuit != enrich.CircuitOpen {
+		t.Fatal("genderize circuit did not open")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name         string
+		schema       fakeSchema
+		providerDown bool
+		status       int
+		report       string
+		failedChecks []string
+	}{
+		{name: "ok", schema: fakeSchema{version: 10}, status: http.StatusOK, report: model.HealthOK},
+		{name: "newer schema", schema: fakeSchema{version: 11}, status: http.StatusOK, report: model.HealthOK},
+		{
+			name: "database down", schema: fakeSchema{down: errors.New("connection refused")},
+			status: http.StatusServiceUnavailable, report: model.HealthUnavailable, failedChecks: []string{"database", "migrations"},
+		},
+		{
+			name: "dirty migration", schema: fakeSchema{version: 10, dirty: true},
+			status: http.StatusServiceUnavailable, report: model.HealthUnavailable, failedChecks: []string{"migrations"},
+		},
+		{
+			name: "older schema", schema: fakeSchema{version: 9},
+			status: http.StatusServiceUnavailable, report: model.HealthUnavailable, failedChecks: []string{"migrations"},
+		},
+		{
+			name: "provider down", schema: fakeSchema{version: 10}, providerDown: true,
+			status: http.StatusOK, report: model.HealthDegraded, failedChecks: []string{enrich.ProviderGenderize},
+		},
+		{
+			// Критичный отказ важнее некритичного
+			name: "database and provider down", schema: fakeSchema{down: errors.New("connection refused")}, providerDown: true,
+			status: http.StatusServiceUnavailable, report: model.HealthUnavailable,
+			failedChecks: []string{"database", "migrations", enrich.ProviderGenderize},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := sqlx.NewDb(sql.OpenDB(&tt.schema), "pgx")
+			defer conn.Close()
+			h := newTestHandler(&db.DB{Conn: conn, SchemaVersion: 10})
+			h.Enricher = enrich.New(logging.Discard())
+			if tt.providerDown {
+				failProvider(t, h.Enricher)
+			}
+
+			rec := doRequest(h.Readyz, http.MethodGet, "/readyz", "", "")
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var report model.HealthReport
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.report {
+				t.Errorf("report status = %q, want %q", report.Status, tt.report)
+			}
+			failed := map[string]bool{}
+			for _, name := range tt.failedChecks {
+				failed[name] = true
+			}
+			for name, check := range report.Checks {
+				if (check.Status == model.HealthFail) != failed[name] {
+					t.Errorf("check %s = %+v", name, check)
+				}
+			}
+			if len(report.Checks) != 5 {
+				t.Errorf("checks = %v, want database, migrations and three providers", report.Checks)
+			}
+		})
+	}
+}
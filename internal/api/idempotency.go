@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/auth"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+
+	// spoolMemoryLimit - тела запросов больше этого размера буферизуются во временном файле
+	spoolMemoryLimit = 1 << 20
+)
+
+// replayedHeaders - заголовки ответа, которые сохраняются для повторов
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// IdempotencyMiddleware повторяет сохраненный ответ на запрос с тем же Idempotency-Key.
+// Ключ действует ttl для пути запроса и субъекта. Повтор ключа с другим телом или параметрами
+// отклоняется с 422, повтор во время выполнения первого запроса - с 409.
+// Ответы 5xx не сохраняются, чтобы запрос можно было повторить.
+// limit - ограничение размера тела, совпадающее с ограничением оборачиваемого обработчика.
+func IdempotencyMiddleware(database *db.DB, ttl time.Duration, limit int64, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !validIdempotencyKey(key) {
+				badRequest(w, r, fmt.Sprintf("%s must be 1 to %d printable ASCII characters", idempotencyKeyHeader, maxIdempotencyKeyLen))
+				return
+			}
+
+			body, hash, err := spoolBody(w, r, limit)
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					writeProblem(w, r, model.Problem{
+						Status: http.StatusRequestEntityTooLarge,
+						Detail: fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit),
+					})
+					return
+				}
+				badRequest(w, r, "failed to read request body")
+				return
+			}
+			defer body.Close()
+			r.Body = body
+
+			// Ключи разных клиентов и арендаторов не пересекаются. Область хранится
+			// хешем: субъект из JWT и путь не ограничены по длине.
+			scope := tenant.FromContext(r.Context()) + " " + r.URL.Path
+			if principal, ok := auth.FromContext(r.Context()); ok {
+				scope = principal.ID + " " + scope
+			}
+			sum := sha256.Sum256([]byte(scope))
+			scope = hex.EncodeToString(sum[:])
+			rec, err := database.AcquireIdempotencyKey(r.Context(), key, scope, hash, ttl)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "failed to acquire idempotency key", "error", err)
+				internalError(w, r)
+				return
+			}
+			if rec != nil {
+				replay(w, r, rec, hash)
+				return
+			}
+
+			// Сохранение не должно зависеть от отключения клиента
+			ctx := context.WithoutCancel(r.Context())
+			capture := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := database.ReleaseIdempotencyKey(ctx, key, scope); err != nil {
+					logger.ErrorContext(ctx, "failed to release idempotency key", "error", err)
+				}
+			}()
+			next.ServeHTTP(capture, r)
+
+			// Ошибки сервера и исчерпанную квоту не сохраняем: повтор может пройти
+			if capture.status >= http.StatusInternalServerError || capture.status == http.StatusTooManyRequests {
+				return
+			}
+			header := http.Header{}
+			for _, name := range replayedHeaders {
+				if v := capture.Header().Values(name); len(v) > 0 {
+					header[name] = v
+				}
+			}
+			headerJSON, _ := json.Marshal(header)
+			if err := database.CompleteIdempotencyKey(ctx, key, scope, capture.status, headerJSON, capture.body.Bytes()); err != nil {
+				logger.ErrorContext(ctx, "failed to store idempotent response", "error", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, rec *db.IdempotencyRecord, hash string) {
+	if rec.RequestHash != hash {
+		writeProblem(w, r, model.Problem{
+			Status: http.StatusUnprocessableEntity,
+			Detail: idempotencyKeyHeader + " has already been used with a different request",
+		})
+		return
+	}
+	if rec.Status == nil {
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, r, model.Problem{
+			Status: http.StatusConflict,
+			Detail: "a request with this " + idempotencyKeyHeader + " is still in progress",
+		})
+		return
+	}
+	var header http.Header
+	json.Unmarshal(rec.Header, &header)
+	for name, values := range header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.Header().Set("Content-Length", strconv.Itoa(len(rec.Body)))
+	w.WriteHeader(*rec.Status)
+	w.Write(rec.Body)
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// spoolBody читает тело запроса не больше limit байт, считая хеш метода, пути, параметров,
+// типа содержимого и тела. Тело до spoolMemoryLimit остается в памяти, большее сохраняется
+// во временный файл, который удаляется при закрытии возвращенного тела.
+func spoolBody(w http.ResponseWriter, r *http.Request, limit int64) (io.ReadCloser, string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Content-Type"))
+
+	src := io.TeeReader(http.MaxBytesReader(w, r.Body, limit), h)
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, src, spoolMemoryLimit); err == io.EOF {
+		return io.NopCloser(&buf), hex.EncodeToString(h.Sum(nil)), nil
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	file, err := os.CreateTemp("", "request-*")
+	if err != nil {
+		return nil, "", err
+	}
+	spool := &tempFile{file}
+	if _, err := io.Copy(file, io.MultiReader(&buf, src)); err != nil {
+		spool.Close()
+		return nil, "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		spool.Close()
+		return nil, "", err
+	}
+	return spool, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// tempFile удаляет временный файл при закрытии
+type tempFile struct{ *os.File }
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// captureWriter запоминает статус и тело ответа, передавая их клиенту
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.status, c.wroteHeader = status, true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	c.wroteHeader = true
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/importer"
+	"github.com/shenikar/Name-analyzer/internal/logging"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxImportSize ограничивает размер загружаемого файла импорта
+const maxImportSize = 100 << 20
+
+// CreateImport godoc
+// @Summary Импортировать людей из файла
+// @Description Принимает файл CSV (с заголовком) или NDJSON и запускает задачу импорта: строки читаются потоково,
+// @Description проверяются, обогащаются и записываются пачками. Прогресс доступен по GET /imports/{id},
+// @Description ошибочные строки - по GET /imports/{id}/errors.
+// @Tags imports
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param file body string true "Содержимое файла"
+// @Param format query string false "Формат файла, по умолчанию определяется по Content-Type" Enums(csv, ndjson)
+// @Param mapping query string false "Сопоставление полей колонкам (ключам NDJSON): name:Имя,surname:Фамилия,patronymic:Отчество"
+// @Param delimiter query string false "Разделитель колонок CSV" default(,)
+// @Param enrich query boolean false "Обогащать данные через внешние API" default(true)
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохраненный ответ"
+// @Success 202 {object} model.Import
+// @Header 202 {string} Location "Адрес задачи импорта"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 413 {object} model.Problem "Файл слишком большой"
+// @Failure 409 {object} model.Problem "Запрос с этим Idempotency-Key еще выполняется"
+// @Failure 422 {object} model.Problem "Idempotency-Key уже использован с другим запросом"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /imports [post]
+func (h *Handler) CreateImport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := importer.Options{Format: q.Get("format"), Delimiter: ',', Enrich: true}
+	if opts.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			opts.Format = importer.FormatCSV
+		case "application/x-ndjson", "application/ndjson", "application/jsonl":
+			opts.Format = importer.FormatNDJSON
+		}
+	}
+	if opts.Format != importer.FormatCSV && opts.Format != importer.FormatNDJSON {
+		badRequest(w, r, "format must be csv or ndjson")
+		return
+	}
+	mapping, err := parseMapping(q.Get("mapping"))
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+	opts.Mapping = mapping
+	if v := q.Get("delimiter"); v != "" {
+		d, size := utf8.DecodeRuneInString(v)
+		if size != len(v) || d == '"' || d == '\r' || d == '\n' {
+			badRequest(w, r, "delimiter must be a single character")
+			return
+		}
+		opts.Delimiter = d
+	}
+	if v := q.Get("enrich"); v != "" {
+		opts.Enrich, err = strconv.ParseBool(v)
+		if err != nil {
+			badRequest(w, r, "enrich must be a boolean")
+			return
+		}
+	}
+
+	// Сохраняем файл во временный, чтобы обработать его после ответа клиенту
+	file, err := os.CreateTemp("", "import-*")
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to create import file", "error", err)
+		internalError(w, r)
+		return
+	}
+	cleanup := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	if _, err := io.Copy(file, http.MaxBytesReader(w, r.Body, maxImportSize)); err != nil {
+		cleanup()
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeProblem(w, r, model.Problem{Status: http.StatusRequestEntityTooLarge, Detail: "file is too large"})
+			return
+		}
+		badRequest(w, r, "failed to read request body")
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		h.Logger.ErrorContext(r.Context(), "failed to rewind import file", "error", err)
+		internalError(w, r)
+		return
+	}
+
+	imp := &model.Import{Format: opts.Format, Mapping: mapping}
+	if err := h.DB.CreateImport(r.Context(), imp); err != nil {
+		cleanup()
+		h.Logger.ErrorContext(r.Context(), "failed to create import", "error", err)
+		internalError(w, r)
+		return
+	}
+	job := *imp
+	h.Importer.Go(func(ctx context.Context) {
+		defer cleanup()
+		// Импорт переживает запрос, но выполняется для его арендатора и с его идентификатором;
+		// трасса импорта отдельная и связана со span запроса
+		ctx = tenant.With(ctx, tenant.FromContext(r.Context()))
+		ctx = logging.WithRequestID(ctx, logging.RequestID(r.Context()))
+		ctx, span := tracer.Start(ctx, "import", trace.WithLinks(trace.LinkFromContext(r.Context())),
+			trace.WithAttributes(attribute.String("import.id", job.ID.String())))
+		defer span.End()
+		h.Importer.Run(ctx, &job, file, opts)
+	})
+
+	w.Header().Set("Location", "/api/v1/imports/"+imp.ID.String())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(imp)
+}
+
+// parseMapping разбирает сопоставление вида field:column[,field:column...].
+// Поля без явного сопоставления ищутся в колонках с тем же именем.
+func parseMapping(s string) (map[string]string, error) {
+	mapping := make(map[string]string, len(importer.Fields))
+	for _, field := range importer.Fields {
+		mapping[field] = field
+	}
+	if s == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, ":")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected field:column", pair)
+		}
+		if !slices.Contains(importer.Fields, field) {
+			return nil, fmt.Errorf("unknown mapping field %q, expected one of %s", field, strings.Join(importer.Fields, ", "))
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// GetImport godoc
+// @Summary Получить состояние задачи импорта
+// @Description Возвращает статус, прогресс и количество ошибочных строк задачи импорта
+// @Tags imports
+// @Produce json
+// @Param id path string true "ID задачи импорта" format(uuid)
+// @Success 200 {object} model.Import
+// @Failure 400 {object} model.Problem "Некорректный ID"
+// @Failure 404 {object} model.Problem "Задача не найдена"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /imports/{id} [get]
+func (h *Handler) GetImport(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		badRequest(w, r, "invalid id")
+		return
+	}
+	imp, err := h.DB.GetImport(r.Context(), id)
+	if err == db.ErrNotFound {
+		notFound(w, r, "import not found")
+		return
+	}
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to get import", "error", err)
+		internalError(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(imp)
+}
+
+// GetImportErrors godoc
+// @Summary Скачать ошибочные строки импорта
+// @Description Возвращает CSV с номером строки файла, причиной ошибки и исходным содержимым строки
+// @Tags imports
+// @Produce text/csv
+// @Param id path string true "ID задачи импорта" format(uuid)
+// @Success 200 {string} string "CSV с колонками row_number, error, raw"
+// @Failure 400 {object} model.Problem "Некорректный ID"
+// @Failure 404 {object} model.Problem "Задача не найдена"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /imports/{id}/errors [get]
+func (h *Handler) GetImportErrors(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		badRequest(w, r, "invalid id")
+		return
+	}
+	if _, err := h.DB.GetImport(r.Context(), id); err == db.ErrNotFound {
+		notFound(w, r, "import not found")
+		return
+	} else if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to get import", "error", err)
+		internalError(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%s-errors.csv"`, id))
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"row_number", "error", "raw"})
+	err = h.DB.EachImportError(r.Context(), id, func(row model.ImportError) error {
+		return cw.Write([]string{strconv.Itoa(row.RowNumber), row.Error, row.Raw})
+	})
+	cw.Flush()
+	if err != nil {
+		// Заголовки уже отправлены, поэтому ошибку можно только залогировать
+		h.Logger.ErrorContext(r.Context(), "failed to write import errors", "error", err)
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/metrics"
+)
+
+// unmatchedRoute - метка маршрута для запросов, не подошедших ни к одному шаблону mux
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware считает запросы и их длительность по шаблону маршрута mux
+// (например "GET /api/v1/persons/{id}") и статусу ответа. Шаблон вместо пути
+// не дает идентификаторам записей порождать новые ряды метрик.
+func MetricsMiddleware(mux *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+			_, route := mux.Handler(r)
+			if route == "" {
+				route = unmatchedRoute
+			}
+			status := strconv.Itoa(sw.status)
+			metrics.HTTPRequests.WithLabelValues(route, status).Inc()
+			metrics.HTTPDuration.WithLabelValues(route, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
@@ -1,28 +1,140 @@
 package api
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/internal/auth"
+	"github.com/shenikar/Name-analyzer/internal/logging"
 )
 
-func LoggingMiddleware(logger *log.Logger) func(http.Handler) http.Handler {
+// maxRequestIDLength ограничивает длину идентификатора запроса, принятого от клиента
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware берет идентификатор запроса из заголовка X-Request-ID или создает новый,
+// сохраняет его в контексте и возвращает в заголовке ответа
+func RequestIDMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-			next.ServeHTTP(w, r)
-			logger.Printf("Request: %s %s %s", r.Method, r.URL.Path, time.Since(start))
+			id := r.Header.Get(logging.RequestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(logging.RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
 		})
 	}
 }
 
-func RecoverMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				http.Error(w, "internal server error", http.StatusInternalServerError)
+// validRequestID допускает непустой идентификатор из печатных символов ASCII без пробелов
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// LoggingMiddleware после ответа пишет в журнал метод, путь, статус, размер ответа,
+// длительность и субъекта запроса
+func LoggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			entry := &logEntry{}
+			ctx := r.Context()
+			next.ServeHTTP(sw, r.WithContext(context.WithValue(ctx, logEntryKey{}, entry)))
+			principal := "-"
+			if entry.principal != nil {
+				principal = entry.principal.ID
+			}
+			level := slog.LevelInfo
+			switch {
+			case sw.status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case probePaths[r.URL.Path]:
+				level = slog.LevelDebug
 			}
-		}()
-		next.ServeHTTP(w, r)
-	})
+			logger.Log(ctx, level, "request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", sw.status,
+				"bytes", sw.bytes,
+				"duration_ms", float64(time.Since(start).Microseconds())/1000,
+				"principal", principal,
+				"remote_addr", r.RemoteAddr,
+			)
+		})
+	}
+}
+
+type logEntryKey struct{}
+
+// logEntry собирает сведения, известные только внутренним слоям: журнал запросов
+// подключен раньше аутентификации, чтобы в него попадали и отклоненные запросы
+type logEntry struct {
+	principal *auth.Principal
+}
+
+// logPrincipal сообщает журналу запросов субъекта, определенного аутентификацией
+func logPrincipal(ctx context.Context, principal *auth.Principal) {
+	if entry, ok := ctx.Value(logEntryKey{}).(*logEntry); ok {
+		entry.principal = principal
+	}
+}
+
+// statusWriter запоминает статус и количество отправленных байт ответа
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// RecoverMiddleware отвечает 500 на панику обработчика и пишет ее в журнал со стеком
+func RecoverMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if err := recover(); err != nil {
+					// Обрыв ответа после отправки заголовков обрабатывает сам net/http
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
+					logger.ErrorContext(r.Context(), "panic while handling request",
+						"error", err, "stack", string(debug.Stack()))
+					internalError(w, r)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/internal/auth"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+func TestRequestID(t *testing.T) {
+	handler := RequestIDMiddleware()(http.HandlerFunc(newTestHandler(nil).CreatePerson))
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"from client", "req-42", true},
+		{"generated", "", false},
+		{"invalid", "bad id", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/persons", strings.NewReader(`{"name":`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			id := rec.Header().Get("X-Request-ID")
+			if id == "" || (id == tt.header) != tt.keep {
+				t.Fatalf("X-Request-ID = %q, sent %q", id, tt.header)
+			}
+			if problem := decodeProblem(t, rec); problem.RequestID != id {
+				t.Fatalf("problem request_id = %q, want %q", problem.RequestID, id)
+			}
+		})
+	}
+}
+
+type stubKeyStore map[string]*model.APIKey
+
+func (s stubKeyStore) GetAPIKeyByHash(_ context.Context, hash string) (*model.APIKey, error) {
+	if key, ok := s[hash]; ok {
+		return key, nil
+	}
+	return nil, db.ErrNotFound
+}
+
+func (s stubKeyStore) TouchAPIKey(context.Context, uuid.UUID) error { return nil }
+
+func TestLoggingCoversAuth(t *testing.T) {
+	key := &model.APIKey{ID: uuid.New(), Name: "test"}
+	store := stubKeyStore{auth.HashKey("good"): key}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := LoggingMiddleware(logger)(RecoverMiddleware(logger)(AuthMiddleware(store, nil, logger)(inner)))
+
+	tests := []struct {
+		name      string
+		path      string
+		token     string
+		status    int
+		principal string
+	}{
+		{"authenticated", "/", "good", http.StatusNoContent, key.ID.String()},
+		{"rejected", "/", "bad", http.StatusUnauthorized, "-"},
+		{"panic", "/panic", "good", http.StatusInternalServerError, key.ID.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			var entry struct {
+				Msg       string `json:"msg"`
+				Status    int    `json:"status"`
+				Principal string `json:"principal"`
+			}
+			for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+				if err := json.Unmarshal(line, &entry); err != nil {
+					t.Fatalf("log line %q: %v", line, err)
+				}
+			}
+			if entry.Msg != "request" || entry.Status != tt.status || entry.Principal != tt.principal {
+				t.Fatalf("last log entry = %+v", entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/shenikar/Name-analyzer/internal/db"
+)
+
+// pageMediaType запрашивает ответ списка в виде конверта model.PersonList
+const pageMediaType = "application/vnd.name-analyzer.page+json"
+
+// envelopeMediaType сообщает, запросил ли клиент конверт вместо голого массива
+// (параметром envelope=true или заголовком Accept с pageMediaType),
+// и возвращает Content-Type ответа
+func envelopeMediaType(r *http.Request) (string, bool) {
+	if v := r.URL.Query().Get("envelope"); v != "" {
+		ok, _ := strconv.ParseBool(v)
+		return "application/json", ok
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == pageMediaType {
+			return pageMediaType, true
+		}
+	}
+	return "", false
+}
+
+// pageLink возвращает ссылку на текущий путь с измененными параметрами запроса
+func pageLink(r *http.Request, set map[string]string) string {
+	q := r.URL.Query()
+	for k, v := range set {
+		q.Set(k, v)
+	}
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// parseSort разбирает параметры sort=field[,field...] и order=asc|desc[,asc|desc...].
+// Одно направление применяется ко всем полям, иначе направления сопоставляются полям по порядку;
+// без order поля сортируются по возрастанию, без sort возвращается nil - порядок db.DefaultSort.
+func parseSort(sort, order string) ([]db.SortKey, error) {
+	if sort == "" {
+		if order != "" {
+			return nil, fmt.Errorf("%w: order requires sort", db.ErrInvalidSort)
+		}
+		return nil, nil
+	}
+	fields := strings.Split(sort, ",")
+	var orders []string
+	if order != "" {
+		orders = strings.Split(order, ",")
+	}
+	if len(orders) > 1 && len(orders) != len(fields) {
+		return nil, fmt.Errorf("%w: got %d sort fields and %d orders", db.ErrInvalidSort, len(fields), len(orders))
+	}
+	keys := make([]db.SortKey, len(fields))
+	for i, f := range fields {
+		keys[i].Field = strings.TrimSpace(f)
+		dir := "asc"
+		if len(orders) == 1 {
+			dir = orders[0]
+		} else if len(orders) > 1 {
+			dir = orders[i]
+		}
+		switch strings.ToLower(strings.TrimSpace(dir)) {
+		case "asc":
+		case "desc":
+			keys[i].Desc = true
+		default:
+			return nil, fmt.Errorf("%w: unknown order %q", db.ErrInvalidSort, dir)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/patch"
+	"github.com/shenikar/Name-analyzer/internal/validate"
+)
+
+const (
+	mergePatchMediaType = "application/merge-patch+json"
+	jsonPatchMediaType  = "application/json-patch+json"
+
+	// maxPatchSize ограничивает размер тела PATCH-запроса
+	maxPatchSize = 64 << 10
+)
+
+// patchDocument - изменяемые поля записи, к которым применяется патч.
+// Отсутствующие поля и null равнозначны.
+type patchDocument struct {
+	Name        *string `json:"name"`
+	Surname     *string `json:"surname"`
+	Patronymic  *string `json:"patronymic"`
+	Age         *int    `json:"age"`
+	Gender      *string `json:"gender"`
+	Nationality *string `json:"nationality"`
+}
+
+// PatchPerson godoc
+// @Summary Частично обновить запись о человеке
+// @Description Применяет к изменяемым полям записи (name, surname, patronymic, age, gender, nationality)
+// @Description JSON Merge Patch (RFC 7396) или JSON Patch (RFC 6902). В отличие от PUT, null или операция remove
+// @Description очищает поле. Имя и фамилия после применения патча обязательны.
+// @Tags persons
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "ID человека" format(uuid)
+// @Param request body object true "Merge patch, например {\"patronymic\": null}, или массив операций JSON Patch"
+// @Success 200 {object} model.Person
+// @Failure 400 {object} model.Problem "Некорректный патч или нарушения проверки полей в результате"
+// @Failure 404 {object} model.Problem "Человек не найден"
+// @Failure 409 {object} model.Problem "Операция test не выполнена"
+// @Failure 413 {object} model.Problem "Слишком большое тело запроса"
+// @Failure 415 {object} model.Problem "Неподдерживаемый Content-Type"
+// @Failure 422 {object} model.Problem "Операцию нельзя применить к записи"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /persons/{id} [patch]
+func (h *Handler) PatchPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		badRequest(w, r, "invalid id")
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch mediaType {
+	case mergePatchMediaType:
+		apply = patch.Merge
+	case jsonPatchMediaType:
+		apply = patch.Apply
+	default:
+		w.Header().Set("Accept-Patch", mergePatchMediaType+", "+jsonPatchMediaType)
+		writeProblem(w, r, model.Problem{Status: http.StatusUnsupportedMediaType, Detail: "content type must be " + mergePatchMediaType + " or " + jsonPatchMediaType})
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeProblem(w, r, model.Problem{
+				Status: http.StatusRequestEntityTooLarge,
+				Detail: fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit),
+			})
+			return
+		}
+		badRequest(w, r, "failed to read request body")
+		return
+	}
+
+	// Патч применяется к заблокированной записи, чтобы параллельный запрос
+	// не перезаписал изменения, сделанные после чтения
+	ctx := r.Context()
+	person, err := h.DB.ModifyPerson(ctx, id, func(person *model.Person) error {
+		doc, _ := json.Marshal(patchDocument{
+			Name:        &person.Name,
+			Surname:     &person.Surname,
+			Patronymic:  person.Patronymic,
+			Age:         person.Age,
+			Gender:      person.Gender,
+			Nationality: person.Nationality,
+		})
+		patched, err := apply(doc, body)
+		switch {
+		case errors.Is(err, patch.ErrTestFailed):
+			return &patchRejection{model.Problem{Status: http.StatusConflict, Detail: err.Error()}}
+		case errors.Is(err, patch.ErrNotApplicable):
+			return &patchRejection{model.Problem{Status: http.StatusUnprocessableEntity, Detail: err.Error()}}
+		case err != nil:
+			return &patchRejection{model.Problem{Status: http.StatusBadRequest, Detail: "invalid patch: " + err.Error()}}
+		}
+
+		var result patchDocument
+		dec := json.NewDecoder(bytes.NewReader(patched))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&result); err != nil {
+			return &patchRejection{model.Problem{Status: http.StatusBadRequest, Detail: "invalid patch result: " + err.Error()}}
+		}
+		if violations := result.apply(person); len(violations) > 0 {
+			return &patchRejection{validationProblem(violations)}
+		}
+		return nil
+	})
+	var rejection *patchRejection
+	if errors.As(err, &rejection) {
+		writeProblem(w, r, rejection.problem)
+		return
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		notFound(w, r, "person not found")
+		return
+	}
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to patch person", "error", err)
+		internalError(w, r)
+		return
+	}
+	h.redact(r, person)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(person)
+}
+
+// patchRejection - ответ клиенту, если патч нельзя применить к записи
+type patchRejection struct {
+	problem model.Problem
+}
+
+func (e *patchRejection) Error() string {
+	return e.problem.Detail
+}
+
+// apply проверяет документ так же, как запрос на создание, и переносит поля в запись
+func (d *patchDocument) apply(person *model.Person) []model.FieldViolation {
+	req := model.PersonRequest{
+		Patronymic:  d.Patronymic,
+		Age:         d.Age,
+		Gender:      d.Gender,
+		Nationality: d.Nationality,
+	}
+	if d.Name != nil {
+		req.Name = *d.Name
+	}
+	if d.Surname != nil {
+		req.Surname = *d.Surname
+	}
+	if violations := validate.PersonRequest(&req, false); len(violations) > 0 {
+		return violations
+	}
+	person.Name, person.Surname = req.Name, req.Surname
+	person.Patronymic = req.Patronymic
+	person.Age = req.Age
+	person.Gender = req.Gender
+	person.Nationality = req.Nationality
+	return nil
+}
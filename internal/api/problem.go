@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/shenikar/Name-analyzer/internal/logging"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+const problemMediaType = "application/problem+json"
+
+// validationProblemType идентифицирует ошибки проверки полей запроса
+const validationProblemType = "/problems/validation"
+
+// writeProblem отправляет ошибку в формате RFC 7807
+func writeProblem(w http.ResponseWriter, r *http.Request, problem model.Problem) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Instance == "" {
+		problem.Instance = r.URL.Path
+	}
+	problem.RequestID = logging.RequestID(r.Context())
+	w.Header().Set("Content-Type", problemMediaType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// writeValidationProblem отправляет 400 со списком нарушений по полям
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, violations []model.FieldViolation) {
+	writeProblem(w, r, validationProblem(violations))
+}
+
+func validationProblem(violations []model.FieldViolation) model.Problem {
+	return model.Problem{
+		Type:   validationProblemType,
+		Title:  "Validation failed",
+		Status: http.StatusBadRequest,
+		Detail: "request contains invalid fields",
+		Errors: violations,
+	}
+}
+
+// internalError отправляет 500; подробности ошибки остаются в журнале,
+// клиент получает идентификатор запроса для поиска записи
+func internalError(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, model.Problem{Status: http.StatusInternalServerError})
+}
+
+// badRequest отправляет 400 с описанием ошибки в формате RFC 7807
+func badRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	writeProblem(w, r, model.Problem{Status: http.StatusBadRequest, Detail: detail})
+}
+
+// notFound отправляет 404 с описанием ошибки в формате RFC 7807
+func notFound(w http.ResponseWriter, r *http.Request, detail string) {
+	writeProblem(w, r, model.Problem{Status: http.StatusNotFound, Detail: detail})
+}
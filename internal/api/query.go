@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/url"
+
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/filter"
+)
+
+// parsePersonFilter разбирает параметры фильтрации списка людей:
+// отдельные поля и выражение filter
+func parsePersonFilter(q url.Values) (db.ListOptions, error) {
+	fields := map[string]interface{}{}
+	if v := q.Get("name"); v != "" {
+		fields["name"] = v
+	}
+	if v := q.Get("surname"); v != "" {
+		fields["surname"] = v
+	}
+	if v := q.Get("gender"); v != "" {
+		fields["gender"] = v
+	}
+	if v := q.Get("nationality"); v != "" {
+		fields["nationality"] = v
+	}
+	if v := q.Get("age_min"); v != "" {
+		fields["age_min"] = v
+	}
+	if v := q.Get("age_max"); v != "" {
+		fields["age_max"] = v
+	}
+	opts := db.ListOptions{Filter: fields}
+	if v := q.Get("filter"); v != "" {
+		expr, err := filter.Parse(v)
+		if err != nil {
+			return opts, err
+		}
+		opts.Expr = expr
+	}
+	return opts, nil
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/auth"
+	"github.com/shenikar/Name-analyzer/internal/metrics"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// RateLimiter списывает токен из ведра key, пополняемого со скоростью rate в секунду до burst.
+// Возвращает, разрешен ли запрос, и сколько токенов осталось.
+type RateLimiter interface {
+	TakeRateLimitToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error)
+}
+
+// Limit - скорость пополнения ведра в запросах в секунду и его емкость
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// routeLimits задает долю базового лимита для тяжелых маршрутов
+var routeLimits = map[string]float64{
+	"POST /api/v1/persons:batch":          0.1,
+	"POST /api/v1/imports":                0.1,
+	"GET /api/v1/persons/export":          0.1,
+	"POST /api/v1/persons/merge":          0.5,
+	"GET /api/v1/stats":                   0.5,
+	"GET /api/v1/stats/timeseries":        0.5,
+	"GET /api/v1/persons/{id}/duplicates": 0.5,
+}
+
+// RateLimitMiddleware ограничивает частоту запросов к API алгоритмом token bucket.
+// Ведра ведутся отдельно для каждого маршрута mux и клиента (субъекта или IP-адреса);
+// для маршрутов из routeLimits лимит уменьшается. Маршруты вне /api/ не ограничиваются.
+// Если хранилище лимитов недоступно, запрос пропускается и учитывается в метрике RateLimitFailOpen.
+func RateLimitMiddleware(limiter RateLimiter, limit Limit, mux *http.ServeMux, logger *slog.Logger) func(http.Handler) http.Handler {
+	return rateLimit(limiter, limit, mux, logger, clientKey)
+}
+
+// IPRateLimitMiddleware ограничивает частоту запросов с одного IP-адреса так же, как RateLimitMiddleware.
+// Подключается до аутентификации, чтобы перебор ключей и токенов тоже упирался в лимит.
+func IPRateLimitMiddleware(limiter RateLimiter, limit Limit, mux *http.ServeMux, logger *slog.Logger) func(http.Handler) http.Handler {
+	return rateLimit(limiter, limit, mux, logger, func(r *http.Request) string {
+		return "addr:" + remoteIP(r)
+	})
+}
+
+func rateLimit(limiter RateLimiter, limit Limit, mux *http.ServeMux, logger *slog.Logger, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, pattern := mux.Handler(r)
+			if !strings.Contains(pattern, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			routeLimit := limit
+			if share, ok := routeLimits[pattern]; ok {
+				routeLimit.Rate *= share
+				routeLimit.Burst = max(1, int(float64(routeLimit.Burst)*share))
+			}
+
+			allowed, tokens, err := limiter.TakeRateLimitToken(r.Context(), pattern+"|"+key(r), routeLimit.Rate, routeLimit.Burst)
+			if err != nil {
+				metrics.RateLimitFailOpen.Inc()
+				logger.WarnContext(r.Context(), "rate limiter error, request allowed", "route", pattern, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Заголовки по draft-ietf-httpapi-ratelimit-headers
+			h := w.Header()
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", routeLimit.Burst, formatSeconds(float64(routeLimit.Burst)/routeLimit.Rate)))
+			h.Set("RateLimit-Limit", strconv.Itoa(routeLimit.Burst))
+			h.Set("RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+			h.Set("RateLimit-Reset", formatSeconds((float64(routeLimit.Burst)-tokens)/routeLimit.Rate))
+			if !allowed {
+				h.Set("Retry-After", formatSeconds((1-tokens)/routeLimit.Rate))
+				writeProblem(w, r, model.Problem{
+					Status: http.StatusTooManyRequests,
+					Detail: "rate limit exceeded, retry after the number of seconds in Retry-After",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey определяет клиента по аутентифицированному субъекту или по IP-адресу
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return "principal:" + principal.ID
+	}
+	return "ip:" + remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// formatSeconds округляет длительность в секундах вверх до целого
+func formatSeconds(s float64) string {
+	return strconv.Itoa(int(math.Ceil(math.Max(0, s))))
+}
+
+// MemoryRateLimiter хранит ведра в памяти процесса; подходит для одного экземпляра сервиса
+type MemoryRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	// now возвращает текущее время; подменяется в тестах
+	now func() time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	rate    float64
+	burst   int
+}
+
+// bucketSweepInterval - как часто удаляются заполненные до краев ведра
+const bucketSweepInterval = time.Minute
+
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{buckets: map[string]*tokenBucket{}, lastSweep: time.Now(), now: time.Now}
+}
+
+func (l *MemoryRateLimiter) TakeRateLimitToken(_ context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) > bucketSweepInterval {
+		// Заполненное ведро равнозначно отсутствующему
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.updated).Seconds()*b.rate >= float64(b.burst) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated, b.rate, b.burst = now, rate, burst
+	if b.tokens < 1 {
+		return false, b.tokens, nil
+	}
+	b.tokens--
+	return true, b.tokens, nil
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shenikar/Name-analyzer/internal/logging"
+	"github.com/shenikar/Name-analyzer/internal/metrics"
+)
+
+// fakeClock - время, которое двигается только вызовом advance
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestLimiter создает MemoryRateLimiter с часами, которыми управляет тест
+func newTestLimiter() (*MemoryRateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	l := NewMemoryRateLimiter()
+	l.now, l.lastSweep = clock.now, clock.t
+	return l, clock
+}
+
+func TestMemoryRateLimiter(t *testing.T) {
+	l, clock := newTestLimiter()
+	take := func() (bool, float64) {
+		allowed, tokens, err := l.TakeRateLimitToken(context.Background(), "k", 2, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return allowed, tokens
+	}
+
+	// Полное ведро пропускает burst запросов подряд
+	for i := 0; i < 3; i++ {
+		if allowed, _ := take(); !allowed {
+			t.Fatalf("request %d within burst denied", i+1)
+		}
+	}
+	if allowed, _ := take(); allowed {
+		t.Fatal("request over burst allowed")
+	}
+	// За 250 мс при 2 запросах в секунду накапливается полтокена - мало для запроса
+	clock.advance(250 * time.Millisecond)
+	if allowed, tokens := take(); allowed || tokens != 0.5 {
+		t.Fatalf("after 250ms: allowed = %v, tokens = %v", allowed, tokens)
+	}
+	clock.advance(250 * time.Millisecond)
+	if allowed, _ := take(); !allowed {
+		t.Fatal("request after refill denied")
+	}
+	// Пополнение не превышает burst
+	clock.advance(time.Hour)
+	if _, tokens := take(); tokens != 2 {
+		t.Fatalf("tokens after long pause = %v, want 2", tokens)
+	}
+	// Ведра разных ключей независимы
+	if allowed, _, _ := l.TakeRateLimitToken(context.Background(), "other", 2, 3); !allowed {
+		t.Fatal("other key denied")
+	}
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) TakeRateLimitToken(context.Context, string, float64, int) (bool, float64, error) {
+	return false, 0, errors.New("store unavailable")
+}
+
+func newRateLimitedMux(limiter RateLimiter, limit Limit) http.Handler {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	mux.HandleFunc("GET /api/v1/persons", ok)
+	mux.HandleFunc("GET /api/v1/stats", ok)
+	mux.HandleFunc("GET /healthz", ok)
+	return RateLimitMiddleware(limiter, limit, mux, logging.Discard())(mux)
+}
+
+func get(handler http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter, clock := newTestLimiter()
+	handler := newRateLimitedMux(limiter, Limit{Rate: 1, Burst: 4})
+
+	rec := get(handler, "/api/v1/persons")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	want := map[string]string{"RateLimit-Limit": "4", "RateLimit-Remaining": "3", "RateLimit-Reset": "1", "RateLimit-Policy": "4;w=4"}
+	for name, value := range want {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		get(handler, "/api/v1/persons")
+	}
+	rec = get(handler, "/api/v1/persons")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status over limit = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want 0", got)
+	}
+	decodeProblem(t, rec)
+
+	clock.advance(time.Second)
+	if rec := get(handler, "/api/v1/persons"); rec.Code != http.StatusNoContent {
+		t.Fatalf("status after refill = %d", rec.Code)
+	}
+}
+
+func TestRateLimitMiddlewareRouteShare(t *testing.T) {
+	limiter, _ := newTestLimiter()
+	handler := newRateLimitedMux(limiter, Limit{Rate: 10, Burst: 4})
+
+	// Доля 0.5 для статистики уменьшает и скорость, и емкость ведра
+	rec := get(handler, "/api/v1/stats")
+	if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Fatalf("RateLimit-Limit = %q, want 2", got)
+	}
+	if got := rec.Header().Get("RateLimit-Policy"); got != "2;w=1" {
+		t.Fatalf("RateLimit-Policy = %q, want 2;w=1", got)
+	}
+	get(handler, "/api/v1/stats")
+	if rec := get(handler, "/api/v1/stats"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status over route limit = %d, want 429", rec.Code)
+	}
+	// Ведра маршрутов независимы
+	if rec := get(handler, "/api/v1/persons"); rec.Code != http.StatusNoContent {
+		t.Fatalf("other route status = %d", rec.Code)
+	}
+	// Маршруты вне /api/ не ограничиваются
+	for i := 0; i < 10; i++ {
+		if rec := get(handler, "/healthz"); rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("healthz limited: status %d", rec.Code)
+		}
+	}
+}
+
+func TestRateLimitMiddlewareFailOpen(t *testing.T) {
+	handler := newRateLimitedMux(failingLimiter{}, Limit{Rate: 1, Burst: 1})
+	before := testutil.ToFloat64(metrics.RateLimitFailOpen)
+	for i := 0; i < 3; i++ {
+		rec := get(handler, "/api/v1/persons")
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("status = %d, want request allowed", rec.Code)
+		}
+		if rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatal("rate limit headers set without a limiter decision")
+		}
+	}
+	if got := testutil.ToFloat64(metrics.RateLimitFailOpen) - before; got != 3 {
+		t.Fatalf("fail-open counter grew by %v, want 3", got)
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/shenikar/Name-analyzer/internal/auth"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/filter"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// RequirePermission пропускает запрос, только если политика разрешает субъекту операцию op
+func RequirePermission(policy *auth.Policy, op string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			unauthorized(w, r, "authentication is required")
+			return
+		}
+		if !policy.Allows(principal, op) {
+			writeProblem(w, r, model.Problem{
+				Status: http.StatusForbidden,
+				Detail: "operation " + op + " is not permitted",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hiddenFields возвращает поля, скрытые от субъекта запроса. Без политики поля не скрываются.
+func (h *Handler) hiddenFields(r *http.Request) map[string]bool {
+	if h.Policy == nil {
+		return nil
+	}
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		principal = &auth.Principal{}
+	}
+	return h.Policy.HiddenFields(principal)
+}
+
+// redact скрывает поля записей, недоступные субъекту запроса
+func (h *Handler) redact(r *http.Request, persons ...*model.Person) {
+	hidden := h.hiddenFields(r)
+	if len(hidden) == 0 {
+		return
+	}
+	for _, p := range persons {
+		if p == nil {
+			continue
+		}
+		if hidden["patronymic"] {
+			p.Patronymic = nil
+		}
+		if hidden["age"] {
+			p.Age = nil
+		}
+		if hidden["gender"] {
+			p.Gender = nil
+		}
+		if hidden["nationality"] {
+			p.Nationality = nil
+		}
+	}
+}
+
+// redactCandidates скрывает поля кандидатов в дубли и причины совпадения по скрытым полям
+func (h *Handler) redactCandidates(r *http.Request, candidates []*model.DuplicateCandidate) {
+	hidden := h.hiddenFields(r)
+	if len(hidden) == 0 {
+		return
+	}
+	for _, c := range candidates {
+		h.redact(r, c.Person)
+		reasons := c.Reasons[:0]
+		for _, reason := range c.Reasons {
+			field, _, _ := strings.Cut(reason, "_")
+			if !hidden[field] {
+				reasons = append(reasons, reason)
+			}
+		}
+		c.Reasons = reasons
+	}
+}
+
+// redactStats убирает распределения по скрытым полям
+func (h *Handler) redactStats(r *http.Request, stats *model.Stats) {
+	hidden := h.hiddenFields(r)
+	if hidden["gender"] {
+		stats.ByGender = []model.StatsBucket{}
+		stats.Completeness.WithGender = 0
+	}
+	if hidden["nationality"] {
+		stats.ByNationality = []model.StatsBucket{}
+		stats.Completeness.WithNationality = 0
+	}
+	if hidden["age"] {
+		stats.ByAge = []model.AgeBucket{}
+		stats.Completeness.WithAge = 0
+	}
+	if hidden["gender"] || hidden["nationality"] {
+		stats.GenderByNationality = []model.CrossTabCell{}
+	}
+	if hidden["gender"] || hidden["nationality"] || hidden["age"] {
+		stats.Completeness.Complete, stats.Completeness.Empty = 0, 0
+	}
+}
+
+// checkHiddenFilter запрещает фильтрацию и сортировку по скрытым полям:
+// по ним можно было бы восстановить скрытые значения.
+// Возвращает список скрытых полей, использованных в запросе.
+func (h *Handler) checkHiddenFilter(r *http.Request, opts db.ListOptions) []string {
+	hidden := h.hiddenFields(r)
+	if len(hidden) == 0 {
+		return nil
+	}
+	used := map[string]bool{}
+	for key := range opts.Filter {
+		used[strings.TrimSuffix(strings.TrimSuffix(key, "_min"), "_max")] = true
+	}
+	if opts.Expr != nil {
+		for _, f := range filter.Fields(opts.Expr) {
+			used[f] = true
+		}
+	}
+	for _, k := range opts.Sort {
+		used[k.Field] = true
+	}
+	var denied []string
+	for f := range used {
+		if hidden[f] {
+			denied = append(denied, f)
+		}
+	}
+	sort.Strings(denied)
+	return denied
+}
+
+// writeHiddenFilterProblem отвечает 403 на фильтр или сортировку по скрытым полям
+func writeHiddenFilterProblem(w http.ResponseWriter, r *http.Request, fields []string) {
+	writeProblem(w, r, model.Problem{
+		Status: http.StatusForbidden,
+		Detail: "filtering or sorting by " + strings.Join(fields, ", ") + " is not permitted",
+	})
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/shenikar/Name-analyzer/internal/auth"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/logging"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+var (
+	viewer = &auth.Principal{ID: "viewer", Roles: []string{"viewer"}}
+	reader = &auth.Principal{ID: "reader", Scopes: []string{auth.ScopePersonsRead}}
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// newPolicyHandler создает обработчик с политикой по умолчанию
+func newPolicyHandler(database *db.DB) *Handler {
+	h := newTestHandler(database)
+	h.Policy = auth.DefaultPolicy()
+	return h
+}
+
+// doAs выполняет запрос от имени субъекта
+func doAs(principal *auth.Principal, handler http.HandlerFunc, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestHiddenFilterForbidden(t *testing.T) {
+	h := newPolicyHandler(nil)
+	handlers := map[string]http.HandlerFunc{
+		"/api/v1/persons?":                   h.ListPersons,
+		"/api/v1/stats?":                     h.GetStats,
+		"/api/v1/persons/export?format=csv&": h.ExportPersons,
+	}
+	tests := []struct {
+		query  string
+		detail string
+	}{
+		{"gender=male", "filtering or sorting by gender is not permitted"},
+		{"age_min=18", "filtering or sorting by age is not permitted"},
+		{"filter=nationality%3DRU%20and%20name%3DIvan", "filtering or sorting by nationality is not permitted"},
+		{"sort=age", "filtering or sorting by age is not permitted"},
+		{"gender=male&age_max=60", "filtering or sorting by age, gender is not permitted"},
+	}
+	for path, handler := range handlers {
+		for _, tt := range tests {
+			// У статистики нет сортировки
+			if path == "/api/v1/stats?" && tt.query == "sort=age" {
+				continue
+			}
+			rec := doAs(viewer, handler, path+tt.query)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("%s%s: status = %d, want 403: %s", path, tt.query, rec.Code, rec.Body)
+			}
+			if problem := decodeProblem(t, rec); problem.Detail != tt.detail {
+				t.Errorf("%s%s: detail = %q, want %q", path, tt.query, problem.Detail, tt.detail)
+			}
+		}
+	}
+}
+
+func TestRedactPerson(t *testing.T) {
+	h := newPolicyHandler(nil)
+	full := func() *model.Person {
+		return &model.Person{
+			Name: "Иван", Surname: "Иванов", Patronymic: ptr("Иванович"),
+			Age: ptr(30), Gender: ptr("male"), Nationality: ptr("RU"),
+		}
+	}
+	for _, principal := range []*auth.Principal{reader, viewer} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/persons", nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+		p := full()
+		h.redact(req, p, nil)
+		if p.Name != "Иван" || p.Surname != "Иванов" {
+			t.Errorf("%s: name and surname must stay visible: %+v", principal.ID, p)
+		}
+		hidden := p.Patronymic == nil && p.Age == nil && p.Gender == nil && p.Nationality == nil
+		visible := p.Patronymic != nil && p.Age != nil && p.Gender != nil && p.Nationality != nil
+		if principal == viewer && !hidden {
+			t.Errorf("viewer sees hidden fields: %+v", p)
+		}
+		if principal == reader && !visible {
+			t.Errorf("reader lost fields: %+v", p)
+		}
+	}
+}
+
+func TestRedactStats(t *testing.T) {
+	h := newPolicyHandler(nil)
+	full := func() *model.Stats {
+		return &model.Stats{
+			Total:               3,
+			ByGender:            []model.StatsBucket{{Key: ptr("male"), Count: 3}},
+			ByNationality:       []model.StatsBucket{{Key: ptr("RU"), Count: 3}},
+			ByAge:               []model.AgeBucket{{From: ptr(30), To: ptr(39), Count: 3}},
+			Completeness:        model.Completeness{WithAge: 3, WithGender: 3, WithNationality: 3, Complete: 3, Empty: 0},
+			GenderByNationality: []model.CrossTabCell{{Nationality: ptr("RU"), Gender: ptr("male"), Count: 3}},
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil)
+	stats := full()
+	h.redactStats(req.WithContext(auth.WithPrincipal(req.Context(), reader)), stats)
+	if len(stats.ByGender) != 1 || len(stats.ByAge) != 1 || stats.Completeness.Complete != 3 {
+		t.Errorf("reader stats redacted: %+v", stats)
+	}
+
+	stats = full()
+	h.redactStats(req.WithContext(auth.WithPrincipal(req.Context(), viewer)), stats)
+	// Пустые распределения сериализуются как [], а не null
+	body, _ := json.Marshal(stats)
+	want := `{"total":3,"by_gender":[],"by_nationality":[],"by_age":[],` +
+		`"completeness":{"with_age":0,"with_gender":0,"with_nationality":0,"complete":0,"empty":0},` +
+		`"gender_by_nationality":[]}`
+	if string(body) != want {
+		t.Errorf("viewer stats = %s, want %s", body, want)
+	}
+}
+
+// TestViewerRedactionDB проверяет ответы GET /persons/{id} и GET /stats для роли viewer.
+// Нужна база с примененными миграциями: TEST_DB_DSN=postgres://...
+func TestViewerRedactionDB(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+	database, err := db.ConnDB(dsn, logging.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Conn.Close()
+	h := newPolicyHandler(database)
+	ctx := context.Background()
+
+	person := &model.Person{
+		Name: "Тест", Surname: "Роли", Patronymic: ptr("Иванович"),
+		Age: ptr(30), Gender: ptr("male"), Nationality: ptr("RU"),
+	}
+	if err := database.CreatePerson(ctx, person); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.DeletePerson(ctx, person.ID) })
+
+	path := "/api/v1/persons/" + person.ID.String()
+	for _, principal := range []*auth.Principal{reader, viewer} {
+		rec := doAs(principal, h.GetPerson, path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", principal.ID, rec.Code, rec.Body)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body["name"] != "Тест" || body["surname"] != "Роли" {
+			t.Errorf("%s: name and surname must stay visible: %v", principal.ID, body)
+		}
+		for _, field := range auth.RedactableFields {
+			if hidden := body[field] == nil; hidden != (principal == viewer) {
+				t.Errorf("%s: %s = %v", principal.ID, field, body[field])
+			}
+		}
+	}
+
+	rec := doAs(viewer, h.GetStats, "/api/v1/stats")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stats: status = %d: %s", rec.Code, rec.Body)
+	}
+	var stats model.Stats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total == 0 {
+		t.Error("viewer must see the total count")
+	}
+	if len(stats.ByGender)+len(stats.ByNationality)+len(stats.ByAge)+len(stats.GenderByNationality) != 0 ||
+		stats.Completeness != (model.Completeness{}) {
+		t.Errorf("viewer stats are not redacted: %+v", stats)
+	}
+}
@@ -1,25 +1,58 @@
 package api
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
+	"github.com/shenikar/Name-analyzer/config"
+	_ "github.com/shenikar/Name-analyzer/docs"
+	"github.com/shenikar/Name-analyzer/internal/auth"
 	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/enrich"
+	"github.com/shenikar/Name-analyzer/internal/importer"
+	"github.com/shenikar/Name-analyzer/internal/metrics"
 	httpSwagger "github.com/swaggo/http-swagger"
-	_ "github.com/shenikar/Name-analyzer/docs"
 )
 
-func RegisterRoutes(mux *http.ServeMux, database *db.DB, logger *log.Logger) {
+func RegisterRoutes(mux *http.ServeMux, database *db.DB, enricher *enrich.Enricher, imports *importer.Importer, cfg *config.Config, policy *auth.Policy, logger *slog.Logger) {
 	h := &Handler{
-		DB:     database,
-		Logger: logger,
+		DB:       database,
+		Enricher: enricher,
+		Importer: imports,
+		Policy:   policy,
+		Logger:   logger,
+	}
+	// Повторы POST-запросов с тем же Idempotency-Key не создают записи повторно
+	// Тело буферизуется с тем же ограничением размера, что и в обработчике маршрута
+	idempotent := func(limit int64, fn http.HandlerFunc) http.Handler {
+		return IdempotencyMiddleware(database, cfg.IdempotencyTTL, limit, logger)(fn)
 	}
-	mux.HandleFunc("POST /api/v1/persons", h.CreatePerson)
-	mux.HandleFunc("GET /api/v1/persons", h.ListPersons)
-	mux.HandleFunc("GET /api/v1/persons/{id}", h.GetPerson)
-	mux.HandleFunc("PUT /api/v1/persons/{id}", h.UpdatePerson)
-	mux.HandleFunc("DELETE /api/v1/persons/{id}", h.DeletePerson)
+	// Каждый маршрут требует разрешения на операцию по политике RBAC
+	allow := func(op string, h http.Handler) http.Handler { return RequirePermission(policy, op, h) }
+
+	mux.Handle("POST /api/v1/persons", allow(auth.OpPersonsCreate, idempotent(maxPersonBodySize, h.CreatePerson)))
+	mux.Handle("POST /api/v1/persons:batch", allow(auth.OpPersonsCreate, idempotent(maxBatchBodySize, h.CreatePersonsBatch)))
+	mux.Handle("GET /api/v1/persons", allow(auth.OpPersonsRead, http.HandlerFunc(h.ListPersons)))
+	mux.Handle("GET /api/v1/persons/export", allow(auth.OpPersonsExport, http.HandlerFunc(h.ExportPersons)))
+	mux.Handle("GET /api/v1/persons/{id}", allow(auth.OpPersonsRead, http.HandlerFunc(h.GetPerson)))
+	mux.Handle("PUT /api/v1/persons/{id}", allow(auth.OpPersonsUpdate, http.HandlerFunc(h.UpdatePerson)))
+	mux.Handle("PATCH /api/v1/persons/{id}", allow(auth.OpPersonsUpdate, http.HandlerFunc(h.PatchPerson)))
+	mux.Handle("DELETE /api/v1/persons/{id}", allow(auth.OpPersonsDelete, http.HandlerFunc(h.DeletePerson)))
+	mux.Handle("GET /api/v1/persons/{id}/duplicates", allow(auth.OpPersonsRead, http.HandlerFunc(h.FindDuplicates)))
+	mux.Handle("POST /api/v1/persons/merge", allow(auth.OpPersonsMerge, idempotent(maxMergeBodySize, h.MergePersons)))
+	mux.Handle("GET /api/v1/stats", allow(auth.OpStatsRead, http.HandlerFunc(h.GetStats)))
+	mux.Handle("GET /api/v1/stats/timeseries", allow(auth.OpStatsRead, http.HandlerFunc(h.GetTimeSeries)))
+	mux.Handle("POST /api/v1/imports", allow(auth.OpPersonsImport, idempotent(maxImportSize, h.CreateImport)))
+	mux.Handle("GET /api/v1/imports/{id}", allow(auth.OpPersonsRead, http.HandlerFunc(h.GetImport)))
+	mux.Handle("GET /api/v1/imports/{id}/errors", allow(auth.OpPersonsRead, http.HandlerFunc(h.GetImportErrors)))
+
+	// Проверки живости и готовности для оркестратора, без аутентификации
+	mux.HandleFunc("GET /healthz", h.Healthz)
+	mux.HandleFunc("GET /readyz", h.Readyz)
+
+	// Метрики Prometheus
+	mux.Handle("GET /metrics", allow(auth.OpMetricsRead, metrics.Handler()))
 
 	// Swagger UI
-    mux.HandleFunc("/swagger/", httpSwagger.WrapHandler)
+	mux.HandleFunc("/swagger/", httpSwagger.WrapHandler)
 }
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// defaultRanges - диапазон временного ряда по умолчанию для каждого интервала
+var defaultRanges = map[string]time.Duration{
+	"hour": 48 * time.Hour,
+	"day":  30 * 24 * time.Hour,
+	"week": 12 * 7 * 24 * time.Hour,
+}
+
+// GetStats godoc
+// @Summary Получить статистику по людям
+// @Description Возвращает распределения по полу, национальности и возрастным интервалам, полноту обогащения
+// @Description и таблицу пола по национальностям для людей, подходящих под фильтры списка
+// @Tags stats
+// @Produce json
+// @Param bucket_width query integer false "Ширина возрастного интервала в годах" default(10) minimum(1) maximum(100)
+// @Param name query string false "Фильтр по имени"
+// @Param surname query string false "Фильтр по фамилии"
+// @Param gender query string false "Фильтр по полу"
+// @Param nationality query string false "Фильтр по национальности"
+// @Param age_min query integer false "Минимальный возраст"
+// @Param age_max query integer false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age>=30 and (gender=female or nationality in (RU,UA))"
+// @Param q query string false "Нечеткий и фонетический поиск по имени и фамилии"
+// @Success 200 {object} model.Stats
+// @Failure 400 {object} model.Problem "Некорректный фильтр или ширина интервала"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /stats [get]
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts, err := parsePersonFilter(q)
+	if err != nil {
+		badRequest(w, r, err.Error())
+		return
+	}
+	opts.Search = strings.TrimSpace(q.Get("q"))
+	if denied := h.checkHiddenFilter(r, opts); len(denied) > 0 {
+		writeHiddenFilterProblem(w, r, denied)
+		return
+	}
+	bucketWidth := 10
+	if v := q.Get("bucket_width"); v != "" {
+		bucketWidth, err = strconv.Atoi(v)
+		if err != nil || bucketWidth < 1 || bucketWidth > 100 {
+			badRequest(w, r, "bucket_width must be an integer from 1 to 100")
+			return
+		}
+	}
+	stats, err := h.DB.PersonStats(r.Context(), opts, bucketWidth)
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to compute stats", "error", err)
+		internalError(w, r)
+		return
+	}
+	h.redactStats(r, stats)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// GetTimeSeries godoc
+// @Summary Получить динамику изменений
+// @Description Возвращает по интервалам количество созданных, измененных и удаленных записей
+// @Description и успешных и неудачных обращений к каждому провайдеру обогащения. Интервалы считаются в UTC,
+// @Description неделя начинается с понедельника. Без from и to возвращаются последние 48 часов, 30 дней или 12 недель.
+// @Tags stats
+// @Produce json
+// @Param interval query string false "Интервал" Enums(hour, day, week) default(day)
+// @Param from query string false "Начало диапазона в формате RFC 3339"
+// @Param to query string false "Конец диапазона в формате RFC 3339, не включается"
+// @Success 200 {object} model.TimeSeries
+// @Failure 400 {object} model.Problem "Некорректный интервал или диапазон"
+// @Failure 401 {object} model.Problem "Нет ключа API или ключ недействителен"
+// @Failure 403 {object} model.Problem "У ключа нет нужной области доступа"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /stats/timeseries [get]
+func (h *Handler) GetTimeSeries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	interval := q.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	rng, ok := defaultRanges[interval]
+	if !ok {
+		badRequest(w, r, "interval must be hour, day or week")
+		return
+	}
+	to := time.Now()
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			badRequest(w, r, "to must be an RFC 3339 timestamp")
+			return
+		}
+		to = t
+	}
+	from := to.Add(-rng)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			badRequest(w, r, "from must be an RFC 3339 timestamp")
+			return
+		}
+		from = t
+	}
+
+	series, err := h.DB.TimeSeries(r.Context(), interval, from, to)
+	if errors.Is(err, db.ErrInvalidInterval) {
+		badRequest(w, r, err.Error())
+		return
+	}
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to compute time series", "error", err)
+		internalError(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+// recordOutcomes сохраняет результаты обогащения; ошибка не влияет на ответ клиенту
+func (h *Handler) recordOutcomes(ctx context.Context, outcomes []model.EnrichmentOutcome) {
+	if err := h.DB.RecordEnrichmentOutcomes(ctx, outcomes); err != nil {
+		h.Logger.ErrorContext(ctx, "failed to record enrichment outcomes", "error", err)
+	}
+}
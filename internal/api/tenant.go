@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/auth"
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+)
+
+// TenantMiddleware определяет арендатора запроса и сохраняет его в контексте. Субъект,
+// привязанный к арендатору, работает только с ним: X-Tenant-ID с другим арендатором - 403.
+// Непривязанный субъект с областью admin выбирает арендатора заголовком X-Tenant-ID,
+// остальные работают с арендатором по умолчанию. Должен выполняться после AuthMiddleware.
+func TenantMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
+			if !ok {
+				// Маршруты API отклонят запрос без субъекта, остальным арендатор не нужен
+				next.ServeHTTP(w, r)
+				return
+			}
+			header := r.Header.Get("X-Tenant-ID")
+			id := tenant.Default
+			switch {
+			case principal.Tenant != "":
+				if header != "" && header != principal.Tenant {
+					writeProblem(w, r, model.Problem{
+						Status: http.StatusForbidden,
+						Detail: "credentials are bound to another tenant",
+					})
+					return
+				}
+				id = principal.Tenant
+			case header != "":
+				if !principal.HasScope(auth.ScopeAdmin) {
+					writeProblem(w, r, model.Problem{
+						Status: http.StatusForbidden,
+						Detail: "X-Tenant-ID requires the admin scope",
+					})
+					return
+				}
+				if !tenant.Valid(header) {
+					badRequest(w, r, "X-Tenant-ID must be 1-64 lowercase letters, digits, '_' or '-'")
+					return
+				}
+				id = header
+			}
+			next.ServeHTTP(w, r.WithContext(tenant.With(r.Context(), id)))
+		})
+	}
+}
+
+// consumeQuota списывает n обогащений из суточной квоты арендатора.
+// Если квота исчерпана, отвечает 429 с Retry-After до начала следующих суток по UTC.
+func (h *Handler) consumeQuota(w http.ResponseWriter, r *http.Request, n int) bool {
+	err := h.DB.ConsumeEnrichmentQuota(r.Context(), n)
+	if errors.Is(err, db.ErrQuotaExceeded) {
+		now := time.Now().UTC()
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		w.Header().Set("Retry-After", strconv.Itoa(int(midnight.Sub(now).Seconds())+1))
+		writeProblem(w, r, model.Problem{
+			Status: http.StatusTooManyRequests,
+			Detail: "daily enrichment quota of the tenant is exhausted",
+		})
+		return false
+	}
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "failed to consume enrichment quota", "error", err)
+		internalError(w, r)
+		return false
+	}
+	return true
+}
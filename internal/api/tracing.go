@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/shenikar/Name-analyzer/internal/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/shenikar/Name-analyzer/internal/api"
+
+// tracer создает спаны фоновых задач через глобальный провайдер
+var tracer = otel.Tracer(tracerName)
+
+// TracingMiddleware начинает серверный span запроса в provider, продолжая трассу из заголовка traceparent.
+// Span называется по шаблону маршрута mux, например "GET /api/v1/persons/{id}";
+// ответы 5xx отмечаются как ошибка.
+func TracingMiddleware(provider trace.TracerProvider, mux *http.ServeMux) func(http.Handler) http.Handler {
+	tracer := provider.Tracer(tracerName)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, pattern := mux.Handler(r)
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			attrs := []attribute.KeyValue{
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				attribute.String("request.id", logging.RequestID(ctx)),
+			}
+			name := r.Method
+			if pattern != "" {
+				route := pattern
+				if _, path, ok := strings.Cut(pattern, " "); ok {
+					route = path
+				}
+				name = r.Method + " " + route
+				attrs = append(attrs, semconv.HTTPRoute(route))
+			}
+			ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+			span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+			if sw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+		})
+	}
+}
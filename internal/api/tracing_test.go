@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/persons", newTestHandler(nil).CreatePerson)
+	handler := TracingMiddleware(provider, mux)(mux)
+	rec := doRequest(handler.ServeHTTP, http.MethodPost, "/api/v1/persons", "application/json", `{"name":`)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if got := spans[0].Name(); got != "POST /api/v1/persons" {
+		t.Fatalf("span name = %q", got)
+	}
+	var status int64
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "http.response.status_code" {
+			status = attr.Value.AsInt64()
+		}
+	}
+	if int(status) != rec.Code {
+		t.Fatalf("span status code = %d, response %d", status, rec.Code)
+	}
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// keyPrefix отличает ключи сервиса от других секретов, например при поиске утечек
+const keyPrefix = "na_"
+
+// GenerateKey создает случайный ключ API. Возвращает сам ключ, его хеш для хранения
+// и видимое начало ключа.
+func GenerateKey() (key, hash, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, HashKey(key), key[:len(keyPrefix)+6], nil
+}
+
+// HashKey возвращает SHA-256 ключа. Ключи случайные и длинные, поэтому медленный хеш не нужен.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseScopes разбирает список областей через запятую
+func ParseScopes(s string) ([]string, error) {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q, must be one of %s", scope, strings.Join(Scopes, ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
@@ -0,0 +1,50 @@
+// Package auth описывает субъекта запроса, области доступа и ключи API.
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Области доступа
+const (
+	ScopePersonsRead  = "persons:read"
+	ScopePersonsWrite = "persons:write"
+	// ScopeMetricsRead разрешает только чтение метрик, например для Prometheus
+	ScopeMetricsRead = "metrics:read"
+	// ScopeAdmin включает все остальные области
+	ScopeAdmin = "admin"
+)
+
+// Scopes перечисляет допустимые области доступа
+var Scopes = []string{ScopePersonsRead, ScopePersonsWrite, ScopeMetricsRead, ScopeAdmin}
+
+// Principal - аутентифицированный субъект запроса
+type Principal struct {
+	// ID - идентификатор ключа API или "jwt:" и subject токена
+	ID   string
+	Name string
+	// Roles - роли из утверждений JWT
+	Roles  []string
+	Scopes []string
+	// Tenant - арендатор, к которому привязан субъект; пустое значение - субъект не привязан
+	Tenant string
+}
+
+// HasScope сообщает, разрешена ли субъекту область scope
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+type principalKey struct{}
+
+// WithPrincipal сохраняет субъекта в контексте запроса
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает субъекта запроса, если он аутентифицирован
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
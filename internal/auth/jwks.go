@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksRefreshInterval - как часто перечитывается JWKS по URL
+	jwksRefreshInterval = 15 * time.Minute
+	// jwksMissInterval - не чаще этого JWKS перечитывается из-за неизвестного kid, например после ротации ключей
+	jwksMissInterval = time.Minute
+	// jwksFetchTimeout ограничивает время загрузки JWKS по URL
+	jwksFetchTimeout = 10 * time.Second
+	maxJWKSSize      = 1 << 20
+)
+
+// jwk - открытый ключ в формате RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey - разобранный ключ JWKS
+type publicKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// parseJWKS разбирает набор ключей RSA, EC (P-256, P-384, P-521) и Ed25519.
+// Ключи неизвестных типов и ключи шифрования пропускаются.
+func parseJWKS(data []byte) ([]publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	var keys []publicKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys = append(keys, publicKey{kid: k.Kid, alg: k.Alg, key: key})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || n.BitLen() < 2048 {
+			return nil, errors.New("RSA key must have at least 2048 bits")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPoint(curve, x, y); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+// checkPoint проверяет, что точка лежит на кривой: crypto/ecdh разбирает только корректные ключи
+func checkPoint(curve elliptic.Curve, x, y *big.Int) error {
+	size := (curve.Params().BitSize + 7) / 8
+	if x.BitLen() > size*8 || y.BitLen() > size*8 {
+		return errors.New("invalid EC point")
+	}
+	point := append([]byte{4}, append(x.FillBytes(make([]byte, size)), y.FillBytes(make([]byte, size))...)...)
+	var c ecdh.Curve
+	switch curve {
+	case elliptic.P256():
+		c = ecdh.P256()
+	case elliptic.P384():
+		c = ecdh.P384()
+	default:
+		c = ecdh.P521()
+	}
+	if _, err := c.NewPublicKey(point); err != nil {
+		return errors.New("invalid EC point")
+	}
+	return nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// JWKS загружает ключи из файла или по URL. Ключи по URL перечитываются в фоне
+// каждые 15 минут, а также при встрече неизвестного kid; пока идет загрузка,
+// проверка подписей использует прежние ключи.
+type JWKS struct {
+	location string
+	client   *http.Client
+
+	mu   sync.Mutex
+	keys []publicKey
+	// attempted - время последней попытки загрузки, успешной или нет
+	attempted time.Time
+	// inflight закрывается по завершении текущей загрузки; nil, если загрузка не идет
+	inflight chan struct{}
+}
+
+// NewJWKS загружает набор ключей из файла или http(s) URL
+func NewJWKS(ctx context.Context, location string) (*JWKS, error) {
+	j := &JWKS{location: location, client: &http.Client{Timeout: jwksFetchTimeout}}
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	j.keys, j.attempted = keys, time.Now()
+	return j, nil
+}
+
+func (j *JWKS) remote() bool {
+	return strings.HasPrefix(j.location, "https://") || strings.HasPrefix(j.location, "http://")
+}
+
+func (j *JWKS) fetch(ctx context.Context) ([]publicKey, error) {
+	var data []byte
+	if j.remote() {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.location, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := j.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+		}
+		if data, err = io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize)); err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(j.location); err != nil {
+			return nil, err
+		}
+	}
+	return parseJWKS(data)
+}
+
+// refresh запускает фоновую загрузку ключей, если она еще не идет. Загрузка не зависит
+// от запроса, который ее вызвал; при ошибке остаются прежние ключи. Вызывается под j.mu.
+func (j *JWKS) refresh() {
+	if j.inflight != nil {
+		return
+	}
+	j.attempted = time.Now()
+	done := make(chan struct{})
+	j.inflight = done
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+		defer cancel()
+		keys, err := j.fetch(ctx)
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if err == nil {
+			j.keys = keys
+		}
+		j.inflight = nil
+	}()
+}
+
+// candidates возвращает ключи, подходящие по kid; без kid - все ключи набора.
+// Если kid неизвестен, ждет текущую загрузку ключей, но не дольше, чем живет ctx.
+func (j *JWKS) candidates(ctx context.Context, kid string) []publicKey {
+	j.mu.Lock()
+	keys := matchKid(j.keys, kid)
+	if !j.remote() {
+		j.mu.Unlock()
+		return keys
+	}
+	since := time.Since(j.attempted)
+	if since > jwksRefreshInterval || len(keys) == 0 && since > jwksMissInterval {
+		j.refresh()
+	}
+	done := j.inflight
+	j.mu.Unlock()
+	if len(keys) > 0 || done == nil {
+		return keys
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return matchKid(j.keys, kid)
+}
+
+func matchKid(keys []publicKey, kid string) []publicKey {
+	if kid == "" {
+		return keys
+	}
+	for _, k := range keys {
+		if k.kid == kid {
+			return []publicKey{k}
+		}
+	}
+	return nil
+}
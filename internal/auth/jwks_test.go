@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestJWKSRefresh проверяет, что неизвестный kid не вызывает загрузку на каждый запрос,
+// а неудачная загрузка учитывается и не сбрасывает прежние ключи
+func TestJWKSRefresh(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64.EncodeToString(pub)},
+	}})
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(set)
+	}))
+	defer srv.Close()
+
+	jwks, err := NewJWKS(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Недавно загруженный набор не перечитывается из-за неизвестного kid
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if keys := jwks.candidates(ctx, "unknown"); len(keys) != 0 {
+				t.Errorf("unknown kid matched %d keys", len(keys))
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("fetches = %d, want 1", n)
+	}
+
+	// После интервала параллельные промахи вызывают одну загрузку
+	failing.Store(true)
+	jwks.mu.Lock()
+	jwks.attempted = time.Now().Add(-2 * jwksMissInterval)
+	jwks.mu.Unlock()
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jwks.candidates(ctx, "unknown")
+		}()
+	}
+	wg.Wait()
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("fetches = %d, want 2", n)
+	}
+
+	// Неудачная попытка тоже откладывает следующую, прежние ключи остаются
+	jwks.candidates(ctx, "unknown")
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("fetches after failed refresh = %d, want 2", n)
+	}
+	if keys := jwks.candidates(ctx, "ed"); len(keys) != 1 {
+		t.Fatalf("cached key lost after failed refresh: %d keys", len(keys))
+	}
+}
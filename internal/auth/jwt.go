@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+)
+
+// ErrInvalidToken - токен не прошел проверку подписи или утверждений
+var ErrInvalidToken = errors.New("invalid token")
+
+// Verifier проверяет JWT: подпись ключом из JWKS, издателя, аудиторию и срок действия,
+// и сопоставляет роли из утверждений областям доступа
+type Verifier struct {
+	Keys *JWKS
+	// Issuer и Audience проверяются, если заданы
+	Issuer   string
+	Audience string
+	// RolesClaim - утверждение с ролями, вложенные поля через точку, например realm_access.roles.
+	// Значение - массив строк или строка через пробел.
+	RolesClaim string
+	// RoleScopes сопоставляет роли областям доступа; роль с именем области дает эту область
+	RoleScopes map[string][]string
+	// TenantClaim - утверждение с арендатором, вложенные поля через точку; пустое значение - tenant
+	TenantClaim string
+	// Leeway - допустимое расхождение часов при проверке exp и nbf
+	Leeway time.Duration
+
+	now func() time.Time
+}
+
+// hashes - хеш-функции алгоритмов подписи
+var hashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// curveBits - размер кривой для алгоритмов ES*
+var curveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+// LooksLikeJWT отличает JWT от ключа API по формату: три части через точку
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify проверяет токен и возвращает субъекта
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	if err := v.verifySignature(ctx, header.Alg, header.Kid, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: sub is required", ErrInvalidToken)
+	}
+	tenantClaim := v.TenantClaim
+	if tenantClaim == "" {
+		tenantClaim = "tenant"
+	}
+	var tenantID string
+	if value := claim(claims, tenantClaim); value != nil {
+		tenantID, _ = value.(string)
+		if !tenant.Valid(tenantID) {
+			return nil, fmt.Errorf("%w: invalid %s", ErrInvalidToken, tenantClaim)
+		}
+	}
+	roles := v.roles(claims)
+	return &Principal{
+		ID:     "jwt:" + sub,
+		Name:   sub,
+		Roles:  roles,
+		Scopes: v.scopes(roles),
+		Tenant: tenantID,
+	}, nil
+}
+
+func (v *Verifier) verifySignature(ctx context.Context, alg, kid string, signed, sig []byte) error {
+	if alg == "" || alg == "none" || strings.HasPrefix(alg, "HS") {
+		return fmt.Errorf("%w: algorithm %q is not allowed", ErrInvalidToken, alg)
+	}
+	for _, k := range v.Keys.candidates(ctx, kid) {
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		if verify(alg, k.key, signed, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+}
+
+// verify проверяет подпись; несовместимый с алгоритмом тип ключа считается неверной подписью
+func verify(alg string, key crypto.PublicKey, signed, sig []byte) bool {
+	if alg == "EdDSA" {
+		k, ok := key.(ed25519.PublicKey)
+		return ok && ed25519.Verify(k, signed, sig)
+	}
+	hash, ok := hashes[alg]
+	if !ok {
+		return false
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(k, hash, digest, sig) == nil
+		case "PS":
+			return rsa.VerifyPSS(k, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		// Подпись ES* - r и s фиксированной длины подряд; кривая должна соответствовать алгоритму
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size || k.Curve.Params().BitSize != curveBits[alg] {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(k, digest, r, s)
+	}
+	return false
+}
+
+func (v *Verifier) checkClaims(claims map[string]any) error {
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return fmt.Errorf("%w: exp is required", ErrInvalidToken)
+	}
+	if !now.Before(exp.Add(v.Leeway)) {
+		return fmt.Errorf("%w: token has expired", ErrInvalidToken)
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(v.Leeway).Before(nbf) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if v.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.Issuer {
+			return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+		}
+	}
+	if v.Audience != "" {
+		var aud []string
+		switch a := claims["aud"].(type) {
+		case string:
+			aud = []string{a}
+		case []any:
+			for _, item := range a {
+				if s, ok := item.(string); ok {
+					aud = append(aud, s)
+				}
+			}
+		}
+		if !slices.Contains(aud, v.Audience) {
+			return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+		}
+	}
+	return nil
+}
+
+// roles извлекает роли из утверждения RolesClaim
+func (v *Verifier) roles(claims map[string]any) []string {
+	path := v.RolesClaim
+	if path == "" {
+		path = "roles"
+	}
+	var roles []string
+	switch r := claim(claims, path).(type) {
+	case string:
+		roles = strings.Fields(r)
+	case []any:
+		for _, item := range r {
+			if s, ok := item.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+	return roles
+}
+
+// claim возвращает значение утверждения по пути через точку или nil, если его нет
+func claim(claims map[string]any, path string) any {
+	var value any = claims
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+func (v *Verifier) scopes(roles []string) []string {
+	var scopes []string
+	for _, role := range roles {
+		mapped, ok := v.RoleScopes[role]
+		if !ok && slices.Contains(Scopes, role) {
+			mapped = []string{role}
+		}
+		for _, scope := range mapped {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+// ParseRoleScopes разбирает сопоставление ролей областям вида "reader=persons:read;editor=persons:read,persons:write"
+func ParseRoleScopes(s string) (map[string][]string, error) {
+	m := map[string][]string{}
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		role, list, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(role) == "" {
+			return nil, fmt.Errorf("invalid role mapping %q, expected role=scope,scope", item)
+		}
+		scopes, err := ParseScopes(list)
+		if err != nil {
+			return nil, fmt.Errorf("role %q: %w", role, err)
+		}
+		m[strings.TrimSpace(role)] = scopes
+	}
+	return m, nil
+}
+
+func decodeSegment(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func numericDate(v any) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var b64 = base64.RawURLEncoding
+
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+	ed  ed25519.PrivateKey
+}
+
+// newTestJWKS создает ключи RSA, EC и Ed25519 и записывает их открытые части во временный файл JWKS
+func newTestJWKS(t *testing.T) (*JWKS, testKeys) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64.EncodeToString(rsaKey.N.Bytes()), "e": b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))), "y": b64.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64.EncodeToString(edPub)},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}}
+	data, _ := json.Marshal(set)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := NewJWKS(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwks, testKeys{rsa: rsaKey, ec: ecKey, ed: edKey}
+}
+
+func sign(t *testing.T, keys testKeys, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	var err error
+	switch alg {
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, keys.rsa, crypto.SHA256, digest[:])
+	case "PS256":
+		sig, err = rsa.SignPSS(rand.Reader, keys.rsa, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, keys.ec, digest[:])
+		if err == nil {
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case "EdDSA":
+		sig = ed25519.Sign(keys.ed, []byte(signed))
+	default:
+		sig = []byte("signature")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64.EncodeToString(sig)
+}
+
+func TestVerifier(t *testing.T) {
+	jwks, keys := newTestJWKS(t)
+	now := time.Unix(1_700_000_000, 0)
+	v := &Verifier{
+		Keys:       jwks,
+		Issuer:     "https://gateway.example",
+		Audience:   "name-analyzer",
+		RolesClaim: "realm_access.roles",
+		RoleScopes: map[string][]string{"editor": {ScopePersonsRead, ScopePersonsWrite}},
+		Leeway:     time.Minute,
+		now:        func() time.Time { return now },
+	}
+	claims := func(override map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":          "user-1",
+			"iss":          "https://gateway.example",
+			"aud":          []string{"other", "name-analyzer"},
+			"exp":          now.Add(time.Hour).Unix(),
+			"realm_access": map[string]any{"roles": []string{"editor", "persons:read", "unknown"}},
+		}
+		for k, val := range override {
+			if val == nil {
+				delete(c, k)
+			} else {
+				c[k] = val
+			}
+		}
+		return c
+	}
+
+	valid := []struct{ alg, kid string }{
+		{"RS256", "rsa"}, {"PS256", "rsa"}, {"ES256", "ec"}, {"EdDSA", "ed"}, {"ES256", ""},
+	}
+	for _, tt := range valid {
+		t.Run("valid "+tt.alg+" kid="+tt.kid, func(t *testing.T) {
+			p, err := v.Verify(context.Background(), sign(t, keys, tt.alg, tt.kid, claims(nil)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.ID != "jwt:user-1" || !slices.Equal(p.Roles, []string{"editor", "persons:read", "unknown"}) {
+				t.Fatalf("unexpected principal %+v", p)
+			}
+			if !slices.Equal(p.Scopes, []string{ScopePersonsRead, ScopePersonsWrite}) || p.HasScope(ScopeAdmin) {
+				t.Fatalf("unexpected scopes %v", p.Scopes)
+			}
+		})
+	}
+
+	tamper := sign(t, keys, "RS256", "rsa", claims(nil))
+	tamper = tamper[:len(tamper)-4] + "AAAA"
+	invalid := []struct {
+		name  string
+		token string
+	}{
+		{"expired", sign(t, keys, "RS256", "rsa", claims(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()}))},
+		{"missing exp", sign(t, keys, "RS256", "rsa", claims(map[string]any{"exp": nil}))},
+		{"not yet valid", sign(t, keys, "RS256", "rsa", claims(map[string]any{"nbf": now.Add(2 * time.Minute).Unix()}))},
+		{"wrong issuer", sign(t, keys, "RS256", "rsa", claims(map[string]any{"iss": "https://evil.example"}))},
+		{"wrong audience", sign(t, keys, "RS256", "rsa", claims(map[string]any{"aud": "other"}))},
+		{"missing sub", sign(t, keys, "RS256", "rsa", claims(map[string]any{"sub": nil}))},
+		{"tampered signature", tamper},
+		{"key of other type", sign(t, keys, "RS256", "ec", claims(nil))},
+		{"unknown kid", sign(t, keys, "RS256", "missing", claims(nil))},
+		{"alg none", sign(t, keys, "none", "", claims(nil))},
+		{"alg HS256", sign(t, keys, "HS256", "rsa", claims(nil))},
+		{"malformed", "a.b"},
+		{"invalid tenant", sign(t, keys, "RS256", "rsa", claims(map[string]any{"tenant": "Team A"}))},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(context.Background(), tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	t.Run("tenant", func(t *testing.T) {
+		p, err := v.Verify(context.Background(), sign(t, keys, "RS256", "rsa", claims(map[string]any{"tenant": "team-a"})))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Tenant != "team-a" {
+			t.Fatalf("tenant = %q, want team-a", p.Tenant)
+		}
+	})
+
+	t.Run("leeway", func(t *testing.T) {
+		token := sign(t, keys, "EdDSA", "ed", claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}))
+		if _, err := v.Verify(context.Background(), token); err != nil {
+			t.Fatalf("token expired within leeway rejected: %v", err)
+		}
+	})
+}
+
+func TestParseRoleScopes(t *testing.T) {
+	m, err := ParseRoleScopes("reader=persons:read; editor=persons:read,persons:write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(m["editor"], []string{ScopePersonsRead, ScopePersonsWrite}) || !slices.Equal(m["reader"], []string{ScopePersonsRead}) {
+		t.Fatalf("unexpected mapping %v", m)
+	}
+	if _, err := ParseRoleScopes("reader=persons:delete"); err == nil {
+		t.Fatal("unknown scope accepted")
+	}
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Операции, разрешение на которые проверяется для маршрутов
+const (
+	OpPersonsRead   = "persons:read"
+	OpPersonsCreate = "persons:create"
+	OpPersonsUpdate = "persons:update"
+	OpPersonsDelete = "persons:delete"
+	OpPersonsMerge  = "persons:merge"
+	OpPersonsImport = "persons:import"
+	OpPersonsExport = "persons:export"
+	OpStatsRead     = "stats:read"
+	OpMetricsRead   = "metrics:read"
+)
+
+// Operations перечисляет допустимые операции; "*" в политике означает все операции
+var Operations = []string{
+	OpPersonsRead, OpPersonsCreate, OpPersonsUpdate, OpPersonsDelete,
+	OpPersonsMerge, OpPersonsImport, OpPersonsExport, OpStatsRead, OpMetricsRead,
+}
+
+// RedactableFields - поля записи, которые можно скрыть; id, имя, фамилия и даты видны всегда
+var RedactableFields = []string{"patronymic", "age", "gender", "nationality"}
+
+// RolePolicy - разрешенные роли операции и видимые ей поля; "*" означает все
+type RolePolicy struct {
+	Operations []string `json:"operations"`
+	Fields     []string `json:"fields"`
+}
+
+// Policy сопоставляет роли разрешениям. Ролями субъекта считаются его роли из JWT
+// и области доступа, поэтому области persons:read, persons:write и admin тоже задаются как роли.
+// Разрешения нескольких ролей объединяются.
+type Policy struct {
+	Roles map[string]RolePolicy `json:"roles"`
+}
+
+// DefaultPolicy сохраняет поведение областей доступа и добавляет роль viewer,
+// которой не видны возраст, пол, национальность и отчество
+func DefaultPolicy() *Policy {
+	all := []string{"*"}
+	return &Policy{Roles: map[string]RolePolicy{
+		ScopeAdmin: {Operations: all, Fields: all},
+		ScopePersonsWrite: {Operations: []string{
+			OpPersonsRead, OpPersonsCreate, OpPersonsUpdate, OpPersonsDelete,
+			OpPersonsMerge, OpPersonsImport, OpPersonsExport, OpStatsRead,
+		}, Fields: all},
+		ScopePersonsRead: {Operations: []string{OpPersonsRead, OpPersonsExport, OpStatsRead}, Fields: all},
+		ScopeMetricsRead: {Operations: []string{OpMetricsRead}, Fields: []string{}},
+		"viewer":         {Operations: []string{OpPersonsRead}, Fields: []string{}},
+	}}
+}
+
+// LoadPolicy читает политику из JSON-файла вида
+// {"roles": {"analyst": {"operations": ["persons:read", "stats:read"], "fields": ["gender"]}}}
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	for role, rp := range p.Roles {
+		for _, op := range rp.Operations {
+			if op != "*" && !slices.Contains(Operations, op) {
+				return nil, fmt.Errorf("role %q: unknown operation %q", role, op)
+			}
+		}
+		for _, f := range rp.Fields {
+			if f != "*" && !slices.Contains(RedactableFields, f) {
+				return nil, fmt.Errorf("role %q: unknown field %q, expected one of %v", role, f, RedactableFields)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// roles возвращает политики ролей и областей субъекта
+func (p *Policy) roles(principal *Principal) []RolePolicy {
+	var out []RolePolicy
+	for _, name := range slices.Concat(principal.Roles, principal.Scopes) {
+		if rp, ok := p.Roles[name]; ok {
+			out = append(out, rp)
+		}
+	}
+	return out
+}
+
+// Allows сообщает, разрешена ли субъекту операция op
+func (p *Policy) Allows(principal *Principal, op string) bool {
+	for _, rp := range p.roles(principal) {
+		if slices.Contains(rp.Operations, op) || slices.Contains(rp.Operations, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// HiddenFields возвращает поля, которые субъекту не видны
+func (p *Policy) HiddenFields(principal *Principal) map[string]bool {
+	hidden := map[string]bool{}
+	for _, f := range RedactableFields {
+		hidden[f] = true
+	}
+	for _, rp := range p.roles(principal) {
+		for _, f := range rp.Fields {
+			if f == "*" {
+				return map[string]bool{}
+			}
+			delete(hidden, f)
+		}
+	}
+	return hidden
+}
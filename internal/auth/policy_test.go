@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPolicyHiddenFields(t *testing.T) {
+	p := &Policy{Roles: map[string]RolePolicy{
+		"viewer":  {Operations: []string{OpPersonsRead}, Fields: []string{}},
+		"analyst": {Operations: []string{OpStatsRead}, Fields: []string{"gender", "age"}},
+		"admin":   {Operations: []string{"*"}, Fields: []string{"*"}},
+	}}
+	all := map[string]bool{"patronymic": true, "age": true, "gender": true, "nationality": true}
+	tests := []struct {
+		name      string
+		principal *Principal
+		hidden    map[string]bool
+		ops       map[string]bool
+	}{
+		{"no roles", &Principal{}, all, map[string]bool{OpPersonsRead: false}},
+		{"unknown role", &Principal{Roles: []string{"guest"}}, all, map[string]bool{OpPersonsRead: false}},
+		{"viewer", &Principal{Roles: []string{"viewer"}}, all, map[string]bool{OpPersonsRead: true, OpStatsRead: false}},
+		{
+			// Разрешения ролей объединяются
+			"viewer and analyst", &Principal{Roles: []string{"viewer", "analyst"}},
+			map[string]bool{"patronymic": true, "nationality": true},
+			map[string]bool{OpPersonsRead: true, OpStatsRead: true, OpPersonsDelete: false},
+		},
+		{
+			// Области доступа считаются ролями
+			"scope as role", &Principal{Roles: []string{"viewer"}, Scopes: []string{"admin"}},
+			map[string]bool{},
+			map[string]bool{OpPersonsDelete: true, OpMetricsRead: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.HiddenFields(tt.principal); !reflect.DeepEqual(got, tt.hidden) {
+				t.Errorf("HiddenFields = %v, want %v", got, tt.hidden)
+			}
+			for op, want := range tt.ops {
+				if got := p.Allows(tt.principal, op); got != want {
+					t.Errorf("Allows(%s) = %v, want %v", op, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", `{"roles":{"analyst":{"operations":["persons:read","stats:read"],"fields":["gender"]}}}`, false},
+		{"unknown operation", `{"roles":{"analyst":{"operations":["persons:drop"]}}}`, true},
+		{"unknown field", `{"roles":{"analyst":{"operations":["*"],"fields":["name"]}}}`, true},
+		{"malformed", `{"roles":`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadPolicy(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadPolicy error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
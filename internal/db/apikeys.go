@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+const apiKeyColumns = `id, name, prefix, scopes, tenant_id, created_at, last_used_at, revoked_at`
+
+// apiKeyRow хранит области доступа одной строкой через пробел, как в таблице
+type apiKeyRow struct {
+	model.APIKey
+	ScopesText string `db:"scopes"`
+}
+
+func (r *apiKeyRow) key() *model.APIKey {
+	r.APIKey.Scopes = strings.Fields(r.ScopesText)
+	return &r.APIKey
+}
+
+// CreateAPIKey сохраняет ключ по его хешу
+func (db *DB) CreateAPIKey(ctx context.Context, key *model.APIKey, hash string) error {
+	key.ID = uuid.New()
+	return db.Conn.QueryRowxContext(ctx, `
+		INSERT INTO api_keys (id, name, prefix, key_hash, scopes, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`, key.ID, key.Name, key.Prefix, hash, strings.Join(key.Scopes, " "), key.Tenant).Scan(&key.CreatedAt)
+}
+
+// GetAPIKeyByHash ищет действующий (не отозванный) ключ по хешу
+func (db *DB) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	var row apiKeyRow
+	err := db.Conn.GetContext(ctx, &row, `
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash=$1 AND revoked_at IS NULL
+	`, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.key(), nil
+}
+
+func (db *DB) ListAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	var rows []apiKeyRow
+	if err := db.Conn.SelectContext(ctx, &rows, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at`); err != nil {
+		return nil, err
+	}
+	keys := make([]*model.APIKey, len(rows))
+	for i := range rows {
+		keys[i] = rows[i].key()
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ; отозванный ключ остается в списке
+func (db *DB) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	res, err := db.Conn.ExecContext(ctx, `UPDATE api_keys SET revoked_at=NOW() WHERE id=$1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey отмечает использование ключа не чаще раза в минуту, чтобы не писать в базу на каждый запрос
+func (db *DB) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	_, err := db.Conn.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at=NOW()
+		WHERE id=$1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id)
+	return err
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// CreatePersons вставляет записи в одной транзакции. В атомарном режиме
+// (partial=false) ошибка любой вставки откатывает всю пачку и возвращается
+// как общая ошибка. В режиме частичного успеха каждая вставка выполняется в
+// своей точке сохранения: неудачные откатываются, их ошибки возвращаются
+// в errs по индексам записей, остальные фиксируются.
+func (db *DB) CreatePersons(ctx context.Context, persons []*model.Person, partial bool) (errs []error, err error) {
+	tx, err := db.begin(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	errs = make([]error, len(persons))
+	for i, person := range persons {
+		if !partial {
+			if err := insertPerson(ctx, tx, person); err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_item`); err != nil {
+			return nil, err
+		}
+		if err := insertPerson(ctx, tx, person); err != nil {
+			errs[i] = err
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_item`); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT batch_item`); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+func insertPerson(ctx context.Context, tx *sqlx.Tx, person *model.Person) error {
+	person.ID = uuid.New()
+	query, args, err := tx.BindNamed(insertPersonQuery, newPersonRecord(ctx, person))
+	if err != nil {
+		return err
+	}
+	return tx.QueryRowxContext(ctx, query, args...).Scan(&person.CreatedAt, &person.UpdatedAt)
+}
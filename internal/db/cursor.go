@@ -0,0 +1,44 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor указывает позицию в списке людей для keyset-пагинации:
+// значения ключей сортировки и id граничной записи
+type Cursor struct {
+	// Sort фиксирует сортировку, для которой выдан курсор
+	Sort   string    `json:"s"`
+	Values []*string `json:"v"`
+	ID     uuid.UUID `json:"i"`
+	// Backward означает выборку страницы, предшествующей позиции курсора
+	Backward bool `json:"b,omitempty"`
+}
+
+// Encode возвращает непрозрачное строковое представление курсора
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor разбирает курсор, полученный от клиента
+func ParseCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.ID == uuid.Nil || len(c.Values) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
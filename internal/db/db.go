@@ -2,20 +2,34 @@ package db
 
 import (
 	"fmt"
+	"log/slog"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 )
 
 type DB struct {
 	Conn *sqlx.DB
+	// RowLevelSecurity включает передачу арендатора в Postgres для политик RLS;
+	// запросы арендатора тогда выполняются в транзакциях
+	RowLevelSecurity bool
+	// DailyEnrichmentQuota - суточная квота обогащений арендатора по умолчанию, 0 - без ограничения
+	DailyEnrichmentQuota int
+	// SchemaVersion - версия схемы, которую ожидает сервис; более старая схема делает сервис неготовым
+	SchemaVersion uint
 }
 
-func ConnDB(dsn string) (*DB, error) {
-	conn, err := sqlx.Open("pgx", dsn)
+// ConnDB подключается к Postgres; запросы пишутся в журнал logger на уровне debug
+// и трассируются спанами OpenTelemetry
+func ConnDB(dsn string, logger *slog.Logger) (*DB, error) {
+	cfg, err := pgx.ParseConfig(dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to db: %w", err)
+		return nil, fmt.Errorf("failed to parse db dsn: %w", err)
 	}
+	cfg.Tracer = multitracer.New(&queryLogger{logger: logger}, queryTracer{})
+	conn := sqlx.NewDb(stdlib.OpenDB(*cfg), "pgx")
 	if err := conn.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping to db: %w", err)
 	}
@@ -0,0 +1,276 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/phonetic"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+)
+
+var ErrInvalidMerge = errors.New("invalid merge")
+
+// duplicateRow - кандидат в дубли вместе с показателями сходства, посчитанными в БД
+type duplicateRow struct {
+	model.Person
+	NameSim           float64 `db:"name_sim"`
+	SurnameSim        float64 `db:"surname_sim"`
+	PatronymicSim     float64 `db:"patronymic_sim"`
+	NamePhonetic      bool    `db:"name_phonetic_match"`
+	SurnamePhonetic   bool    `db:"surname_phonetic_match"`
+	PatronymicPresent bool    `db:"patronymic_present"`
+}
+
+// FindDuplicates возвращает записи, похожие на person по имени, фамилии и отчеству
+// (точно, нечетко или фонетически) с учетом близости возраста и национальности,
+// упорядоченные по убыванию уверенности
+func (db *DB) FindDuplicates(ctx context.Context, person *model.Person, limit int) ([]*model.DuplicateCandidate, error) {
+	query := `
+		SELECT ` + personColumns + `,
+			CAST(similarity(name, :name) AS DOUBLE PRECISION) AS name_sim,
+			CAST(similarity(surname, :surname) AS DOUBLE PRECISION) AS surname_sim,
+			CAST(COALESCE(similarity(patronymic, :patronymic), 0) AS DOUBLE PRECISION) AS patronymic_sim,
+			COALESCE(name_phonetic = :name_key, false) AS name_phonetic_match,
+			COALESCE(surname_phonetic = :surname_key, false) AS surname_phonetic_match,
+			(patronymic IS NOT NULL AND CAST(:patronymic AS TEXT) IS NOT NULL) AS patronymic_present
+		FROM persons
+		WHERE id <> :id AND tenant_id = :tenant_id
+			AND (surname % :surname OR surname_phonetic = :surname_key)
+			AND (name % :name OR name_phonetic = :name_key)
+		ORDER BY similarity(surname, :surname) + similarity(name, :name) DESC, id
+		LIMIT 100
+	`
+	args := map[string]interface{}{
+		"id":          person.ID,
+		"tenant_id":   tenant.FromContext(ctx),
+		"name":        person.Name,
+		"surname":     person.Surname,
+		"patronymic":  person.Patronymic,
+		"name_key":    phonetic.Key(person.Name),
+		"surname_key": phonetic.Key(person.Surname),
+	}
+	var candidates []*model.DuplicateCandidate
+	err := db.scoped(ctx, func(q queryer) error {
+		rows, err := sqlx.NamedQueryContext(ctx, q, query, args)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var row duplicateRow
+			if err := rows.StructScan(&row); err != nil {
+				return err
+			}
+			candidate := scoreDuplicate(person, &row)
+			if candidate.Score >= 0.5 {
+				candidates = append(candidates, candidate)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// scoreDuplicate оценивает вероятность того, что row описывает того же человека.
+// Основной вес имеют фамилия и имя, отчество, возраст и национальность уточняют оценку.
+func scoreDuplicate(person *model.Person, row *duplicateRow) *model.DuplicateCandidate {
+	var reasons []string
+	nameScore := func(field string, sim float64, phoneticMatch, exact bool) float64 {
+		switch {
+		case exact:
+			reasons = append(reasons, field+"_exact")
+			return 1
+		case phoneticMatch:
+			reasons = append(reasons, field+"_phonetic")
+			return max(sim, 0.85)
+		default:
+			reasons = append(reasons, field+"_similar")
+			return sim
+		}
+	}
+
+	score := 0.45*nameScore("surname", row.SurnameSim, row.SurnamePhonetic, strings.EqualFold(row.Surname, person.Surname)) +
+		0.35*nameScore("name", row.NameSim, row.NamePhonetic, strings.EqualFold(row.Name, person.Name))
+
+	// Отчество учитывается, только если оно есть у обеих записей
+	weight := 0.8
+	if row.PatronymicPresent {
+		weight += 0.1
+		if strings.EqualFold(*row.Patronymic, *person.Patronymic) {
+			reasons = append(reasons, "patronymic_exact")
+			score += 0.1
+		} else {
+			score += 0.1 * row.PatronymicSim
+			if row.PatronymicSim < 0.3 {
+				reasons = append(reasons, "patronymic_differs")
+			}
+		}
+	}
+	if row.Age != nil && person.Age != nil {
+		weight += 0.05
+		diff := *row.Age - *person.Age
+		if diff < 0 {
+			diff = -diff
+		}
+		switch {
+		case diff <= 2:
+			reasons = append(reasons, "age_close")
+			score += 0.05
+		case diff <= 10:
+			score += 0.05 * float64(10-diff) / 8
+		default:
+			reasons = append(reasons, "age_differs")
+		}
+	}
+	if row.Nationality != nil && person.Nationality != nil {
+		weight += 0.05
+		if strings.EqualFold(*row.Nationality, *person.Nationality) {
+			reasons = append(reasons, "nationality_same")
+			score += 0.05
+		} else {
+			reasons = append(reasons, "nationality_differs")
+		}
+	}
+
+	found := row.Person
+	return &model.DuplicateCandidate{
+		Person:  &found,
+		Score:   float64(int(score/weight*1000+0.5)) / 1000,
+		Reasons: reasons,
+	}
+}
+
+// mergeFields перечисляет поля, которые можно выбирать при слиянии
+var mergeFields = []string{"name", "surname", "patronymic", "age", "gender", "nationality"}
+
+// MergePersons сливает записи sourceIDs в targetID в одной транзакции: значения
+// полей выбираются по fields или берутся из целевой записи с заполнением пустых
+// значений из источников. Снимки записей до слияния сохраняются в person_merges,
+// источники удаляются.
+func (db *DB) MergePersons(ctx context.Context, targetID uuid.UUID, sourceIDs []uuid.UUID, fields map[string]uuid.UUID) (*model.Person, error) {
+	if len(sourceIDs) == 0 {
+		return nil, fmt.Errorf("%w: no source records", ErrInvalidMerge)
+	}
+	ids := append([]uuid.UUID{targetID}, sourceIDs...)
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: record %s is listed twice", ErrInvalidMerge, id)
+		}
+		seen[id] = true
+	}
+	for field, id := range fields {
+		if !slices.Contains(mergeFields, field) {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidMerge, field)
+		}
+		if !seen[id] {
+			return nil, fmt.Errorf("%w: field %q refers to record %s which is not merged", ErrInvalidMerge, field, id)
+		}
+	}
+
+	tx, err := db.begin(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Блокируем записи в порядке id, чтобы параллельные слияния не взаимоблокировались
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+	tenantID := tenant.FromContext(ctx)
+	var locked []*model.Person
+	err = tx.SelectContext(ctx, &locked,
+		`SELECT `+personColumns+` FROM persons WHERE id = ANY(CAST($1 AS UUID[])) AND tenant_id=$2 ORDER BY id FOR UPDATE`,
+		idStrings, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[uuid.UUID]*model.Person, len(ids))
+	for _, person := range locked {
+		records[person.ID] = person
+	}
+	for _, id := range ids {
+		if records[id] == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+	}
+
+	target := records[targetID]
+	before, _ := json.Marshal(target)
+	merged := *target
+	for _, id := range sourceIDs {
+		source := records[id]
+		if merged.Patronymic == nil {
+			merged.Patronymic = source.Patronymic
+		}
+		if merged.Age == nil {
+			merged.Age = source.Age
+		}
+		if merged.Gender == nil {
+			merged.Gender = source.Gender
+		}
+		if merged.Nationality == nil {
+			merged.Nationality = source.Nationality
+		}
+	}
+	for field, id := range fields {
+		from := records[id]
+		switch field {
+		case "name":
+			merged.Name = from.Name
+		case "surname":
+			merged.Surname = from.Surname
+		case "patronymic":
+			merged.Patronymic = from.Patronymic
+		case "age":
+			merged.Age = from.Age
+		case "gender":
+			merged.Gender = from.Gender
+		case "nationality":
+			merged.Nationality = from.Nationality
+		}
+	}
+
+	for _, id := range sourceIDs {
+		snapshot, _ := json.Marshal(records[id])
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO person_merges (tenant_id, target_id, source_id, target_before, source_snapshot) VALUES ($1, $2, $3, $4, $5)`,
+			tenantID, targetID, id, before, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, deletePersonQuery, id, tenantID, "merged"); err != nil {
+			return nil, err
+		}
+	}
+	query, args, err := tx.BindNamed(updatePersonQuery, newPersonRecord(ctx, &merged))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&merged.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
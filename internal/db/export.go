@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// exportFetchSize - количество строк, получаемых из курсора за один FETCH
+const exportFetchSize = 1000
+
+// ExportPersons передает в fn все записи, подходящие под фильтр opts, читая
+// их пачками из серверного курсора в читающей транзакции. Limit, Offset
+// и Cursor игнорируются.
+func (db *DB) ExportPersons(ctx context.Context, opts ListOptions, fn func(*model.Person) error) error {
+	keys := opts.Sort
+	if len(keys) == 0 {
+		keys = DefaultSort
+	}
+	if err := validateSort(keys); err != nil {
+		return err
+	}
+	where, args := personFilter(ctx, opts)
+	query, params, err := db.Conn.BindNamed(
+		`DECLARE export_cursor NO SCROLL CURSOR FOR SELECT `+personColumns+` FROM persons WHERE `+where+
+			` ORDER BY `+orderBy(keys, false), args)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.begin(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, query, params...); err != nil {
+		return err
+	}
+	for {
+		var batch []*model.Person
+		if err := tx.SelectContext(ctx, &batch, fmt.Sprintf("FETCH FORWARD %d FROM export_cursor", exportFetchSize)); err != nil {
+			return err
+		}
+		for _, person := range batch {
+			if err := fn(person); err != nil {
+				return err
+			}
+		}
+		if len(batch) < exportFetchSize {
+			break
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `CLOSE export_cursor`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
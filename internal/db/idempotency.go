@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// IdempotencyRecord - сохраненный результат запроса с заголовком Idempotency-Key.
+// Status равен nil, пока первый запрос еще выполняется.
+type IdempotencyRecord struct {
+	Key         string `db:"key"`
+	Scope       string `db:"scope"`
+	RequestHash string `db:"request_hash"`
+	Status      *int   `db:"status"`
+	Header      []byte `db:"header"`
+	Body        []byte `db:"body"`
+}
+
+// AcquireIdempotencyKey резервирует ключ в области scope на время ttl. Если ключ свободен
+// или его срок истек, возвращает nil; иначе - уже сохраненную запись.
+func (db *DB) AcquireIdempotencyKey(ctx context.Context, key, scope, hash string, ttl time.Duration) (*IdempotencyRecord, error) {
+	var acquired bool
+	err := db.Conn.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (key, scope, request_hash, expires_at)
+		VALUES ($1, $2, $3, NOW() + CAST($4 AS BIGINT) * INTERVAL '1 millisecond')
+		ON CONFLICT (key, scope) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash, status = NULL, header = NULL, body = NULL,
+				created_at = NOW(), expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= NOW()
+		RETURNING true
+	`, key, scope, hash, ttl.Milliseconds()).Scan(&acquired)
+	if err == nil {
+		return nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	var rec IdempotencyRecord
+	err = db.Conn.GetContext(ctx, &rec, `
+		SELECT key, scope, request_hash, status, header, body
+		FROM idempotency_keys WHERE key=$1 AND scope=$2
+	`, key, scope)
+	if err == sql.ErrNoRows {
+		// Запись удалили между запросами, например после ошибки первого запроса
+		return db.AcquireIdempotencyKey(ctx, key, scope, hash, ttl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ для повторов запроса
+func (db *DB) CompleteIdempotencyKey(ctx context.Context, key, scope string, status int, header, body []byte) error {
+	_, err := db.Conn.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status=$3, header=$4, body=$5 WHERE key=$1 AND scope=$2
+	`, key, scope, status, header, body)
+	return err
+}
+
+// ReleaseIdempotencyKey освобождает ключ, чтобы запрос можно было повторить
+func (db *DB) ReleaseIdempotencyKey(ctx context.Context, key, scope string) error {
+	_, err := db.Conn.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key=$1 AND scope=$2`, key, scope)
+	return err
+}
+
+// PurgeIdempotencyKeys удаляет записи с истекшим сроком хранения
+func (db *DB) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	res, err := db.Conn.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
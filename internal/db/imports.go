@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/phonetic"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+)
+
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+const importColumns = `id, format, status, mapping, total_rows, imported_rows, failed_rows, error, created_at, updated_at, finished_at`
+
+// importRow хранит сопоставление колонок в виде JSON, как в таблице
+type importRow struct {
+	model.Import
+	MappingJSON []byte `db:"mapping"`
+}
+
+func (db *DB) CreateImport(ctx context.Context, imp *model.Import) error {
+	mapping, err := json.Marshal(imp.Mapping)
+	if err != nil {
+		return err
+	}
+	imp.ID = uuid.New()
+	imp.Status = ImportPending
+	return db.scoped(ctx, func(q queryer) error {
+		return q.QueryRowxContext(ctx, `
+			INSERT INTO imports (id, tenant_id, format, status, mapping)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING created_at, updated_at
+		`, imp.ID, tenant.FromContext(ctx), imp.Format, imp.Status, mapping).Scan(&imp.CreatedAt, &imp.UpdatedAt)
+	})
+}
+
+func (db *DB) GetImport(ctx context.Context, id uuid.UUID) (*model.Import, error) {
+	var row importRow
+	err := db.scoped(ctx, func(q queryer) error {
+		return q.GetContext(ctx, &row, `SELECT `+importColumns+` FROM imports WHERE id=$1 AND tenant_id=$2`,
+			id, tenant.FromContext(ctx))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(row.MappingJSON, &row.Import.Mapping); err != nil {
+		return nil, err
+	}
+	return &row.Import, nil
+}
+
+// UpdateImport сохраняет статус и счетчики задачи импорта
+func (db *DB) UpdateImport(ctx context.Context, imp *model.Import) error {
+	if imp.Status == ImportCompleted || imp.Status == ImportFailed {
+		now := time.Now()
+		imp.FinishedAt = &now
+	}
+	return db.scoped(ctx, func(q queryer) error {
+		return q.QueryRowxContext(ctx, `
+			UPDATE imports SET status=$3, total_rows=$4, imported_rows=$5, failed_rows=$6, error=$7, finished_at=$8, updated_at=NOW()
+			WHERE id=$1 AND tenant_id=$2
+			RETURNING updated_at
+		`, imp.ID, tenant.FromContext(ctx), imp.Status, imp.TotalRows, imp.ImportedRows, imp.FailedRows, imp.Error, imp.FinishedAt).Scan(&imp.UpdatedAt)
+	})
+}
+
+// FailInterruptedImports отмечает ошибкой задачи, оставшиеся незавершенными после остановки сервиса:
+// импорт выполняется в процессе, запустившем его, и после перезапуска продолжен не будет
+func (db *DB) FailInterruptedImports(ctx context.Context) (int64, error) {
+	var n int64
+	err := db.maintenance(ctx, func(q queryer) error {
+		res, err := q.ExecContext(ctx, `
+			UPDATE imports SET status=$1, error=$2, finished_at=NOW(), updated_at=NOW()
+			WHERE status IN ($3, $4)
+		`, ImportFailed, "import was interrupted by a service restart", ImportPending, ImportRunning)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n, err
+}
+
+func (db *DB) AddImportErrors(ctx context.Context, importID uuid.UUID, rows []model.ImportError) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.copyFrom(ctx, "import_errors", []string{"import_id", "row_number", "raw", "error"}, len(rows), func(i int) ([]any, error) {
+		return []any{importID, rows[i].RowNumber, rows[i].Raw, rows[i].Error}, nil
+	})
+}
+
+// EachImportError вызывает fn для каждой ошибочной строки импорта арендатора по порядку номеров строк
+func (db *DB) EachImportError(ctx context.Context, importID uuid.UUID, fn func(model.ImportError) error) error {
+	return db.scoped(ctx, func(q queryer) error {
+		rows, err := q.QueryxContext(ctx, `
+			SELECT e.row_number, e.raw, e.error FROM import_errors e
+			JOIN imports i ON i.id = e.import_id
+			WHERE e.import_id=$1 AND i.tenant_id=$2
+			ORDER BY e.row_number
+		`, importID, tenant.FromContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var row model.ImportError
+			if err := rows.StructScan(&row); err != nil {
+				return err
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// CopyPersons вставляет записи арендатора из ctx через COPY. Время создания и ID заполняются на стороне приложения.
+func (db *DB) CopyPersons(ctx context.Context, persons []*model.Person) error {
+	now := time.Now()
+	for _, person := range persons {
+		person.ID = uuid.New()
+		person.CreatedAt = now
+		person.UpdatedAt = now
+	}
+	tenantID := tenant.FromContext(ctx)
+	columns := []string{"id", "tenant_id", "name", "surname", "patronymic", "age", "gender", "nationality",
+		"name_phonetic", "surname_phonetic", "created_at", "updated_at"}
+	err := db.copyFrom(ctx, "persons", columns, len(persons), func(i int) ([]any, error) {
+		p := persons[i]
+		return []any{p.ID, tenantID, p.Name, p.Surname, p.Patronymic, p.Age, p.Gender, p.Nationality,
+			phonetic.Key(p.Name), phonetic.Key(p.Surname), p.CreatedAt, p.UpdatedAt}, nil
+	})
+	if err != nil {
+		return err
+	}
+	return db.copyFrom(ctx, "person_events", []string{"tenant_id", "person_id", "kind", "occurred_at"}, len(persons), func(i int) ([]any, error) {
+		return []any{tenantID, persons[i].ID, "created", now}, nil
+	})
+}
+
+// copyFrom выполняет COPY через соединение pgx, лежащее под database/sql.
+// Postgres не поддерживает COPY FROM в таблицы с RLS, поэтому с RLS строки вставляются через INSERT.
+func (db *DB) copyFrom(ctx context.Context, table string, columns []string, n int, row func(i int) ([]any, error)) error {
+	if db.RowLevelSecurity {
+		return db.insertRows(ctx, table, columns, n, row)
+	}
+	conn, err := db.Conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		pgxConn := driverConn.(*stdlib.Conn).Conn()
+		_, err := pgxConn.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromSlice(n, row))
+		return err
+	})
+}
+
+// insertRows вставляет строки многострочными INSERT в одной транзакции арендатора
+func (db *DB) insertRows(ctx context.Context, table string, columns []string, n int, row func(i int) ([]any, error)) error {
+	// Postgres принимает не больше 65535 параметров в запросе
+	perQuery := 65535 / len(columns)
+	return db.scoped(ctx, func(q queryer) error {
+		for start := 0; start < n; start += perQuery {
+			end := min(start+perQuery, n)
+			var (
+				query strings.Builder
+				args  []any
+			)
+			fmt.Fprintf(&query, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+			for i := start; i < end; i++ {
+				values, err := row(i)
+				if err != nil {
+					return err
+				}
+				if i > start {
+					query.WriteString(", ")
+				}
+				query.WriteString("(")
+				for j, v := range values {
+					args = append(args, v)
+					if j > 0 {
+						query.WriteString(", ")
+					}
+					fmt.Fprintf(&query, "$%d", len(args))
+				}
+				query.WriteString(")")
+			}
+			if _, err := q.ExecContext(ctx, query.String(), args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
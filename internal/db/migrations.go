@@ -1,19 +1,27 @@
 package db
 
 import (
+	"context"
 	"errors"
-	"log"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/shenikar/Name-analyzer/migrations"
 )
 
-func RunMigrations(dsn string) error {
-	m, err := migrate.New(
-		"file://./migrations",
-		dsn,
-	)
+// RunMigrations применяет миграции, встроенные в сервис
+func RunMigrations(dsn string, logger *slog.Logger) error {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return err
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", source, dsn)
 	if err != nil {
 		return err
 	}
@@ -22,6 +30,52 @@ func RunMigrations(dsn string) error {
 	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
 		return err
 	}
-	log.Println("migrations applied successfully")
+	version, _, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+	logger.Info("migrations applied", "version", version)
 	return nil
 }
+
+// LatestMigration возвращает номер старшей встроенной миграции - версию схемы, которую ожидает сервис.
+// Версия, примененная к БД, для этого не годится: при схеме новее сервиса она завысила бы ожидание
+func LatestMigration() (uint, error) {
+	return latestMigration(migrations.FS)
+}
+
+func latestMigration(fsys fs.FS) (uint, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+		latest = max(latest, uint(version))
+	}
+	if latest == 0 {
+		return 0, errors.New("no migrations found")
+	}
+	return latest, nil
+}
+
+// MigrationVersion возвращает версию схемы в БД и признак незавершенной миграции
+func (db *DB) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var row struct {
+		Version int64 `db:"version"`
+		Dirty   bool  `db:"dirty"`
+	}
+	if err := db.Conn.GetContext(ctx, &row, `SELECT version, dirty FROM schema_migrations LIMIT 1`); err != nil {
+		return 0, false, err
+	}
+	return uint(row.Version), row.Dirty, nil
+}
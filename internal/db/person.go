@@ -2,39 +2,99 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shenikar/Name-analyzer/internal/filter"
 	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/phonetic"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
 )
 
 var ErrNotFound = errors.New("person not found")
 
+// personColumns перечисляет колонки persons, отображаемые в model.Person
+const personColumns = `id, name, surname, patronymic, age, gender, nationality, created_at, updated_at`
+
+// Изменения записей фиксируются в person_events в том же запросе, что и само изменение
+
+// insertPersonQuery вставляет personRecord и возвращает время создания и обновления
+const insertPersonQuery = `
+	WITH inserted AS (
+		INSERT INTO persons (id, tenant_id, name, surname, patronymic, age, gender, nationality, name_phonetic, surname_phonetic, created_at, updated_at)
+		VALUES (:id, :tenant_id, :name, :surname, :patronymic, :age, :gender, :nationality, :name_phonetic, :surname_phonetic, NOW(), NOW())
+		RETURNING id, tenant_id, created_at, updated_at
+	), logged AS (
+		INSERT INTO person_events (tenant_id, person_id, kind, occurred_at) SELECT tenant_id, id, 'created', created_at FROM inserted
+	)
+	SELECT created_at, updated_at FROM inserted
+`
+
+// updatePersonQuery сохраняет поля personRecord и возвращает время обновления
+const updatePersonQuery = `
+	WITH updated AS (
+		UPDATE persons SET name=:name, surname=:surname, patronymic=:patronymic, age=:age, gender=:gender, nationality=:nationality,
+			name_phonetic=:name_phonetic, surname_phonetic=:surname_phonetic, updated_at=NOW()
+		WHERE id=:id AND tenant_id=:tenant_id
+		RETURNING id, tenant_id, updated_at
+	), logged AS (
+		INSERT INTO person_events (tenant_id, person_id, kind, occurred_at) SELECT tenant_id, id, 'updated', updated_at FROM updated
+	)
+	SELECT updated_at FROM updated
+`
+
+// deletePersonQuery удаляет запись $1 арендатора $2 и фиксирует событие вида $3: deleted или merged
+const deletePersonQuery = `
+	WITH deleted AS (DELETE FROM persons WHERE id=$1 AND tenant_id=$2 RETURNING id, tenant_id)
+	INSERT INTO person_events (tenant_id, person_id, kind) SELECT tenant_id, id, $3 FROM deleted
+`
+
+// personRecord дополняет model.Person служебными колонками таблицы
+type personRecord struct {
+	*model.Person
+	TenantID        string `db:"tenant_id"`
+	NamePhonetic    string `db:"name_phonetic"`
+	SurnamePhonetic string `db:"surname_phonetic"`
+}
+
+// newPersonRecord готовит запись арендатора из ctx к сохранению
+func newPersonRecord(ctx context.Context, person *model.Person) personRecord {
+	return personRecord{
+		Person:          person,
+		TenantID:        tenant.FromContext(ctx),
+		NamePhonetic:    phonetic.Key(person.Name),
+		SurnamePhonetic: phonetic.Key(person.Surname),
+	}
+}
+
 func (db *DB) CreatePerson(ctx context.Context, person *model.Person) error {
-	query := `
-	     INSERT INTO persons (id, name, surname, patronymic, age, gender, nationality, created_at, updated_at)
-		 VALUES (:id, :name, :surname, :patronymic, :age, :gender, :nationality, NOW(), NOW())
-		 RETURNING created_at, updated_at
-	`
 	person.ID = uuid.New()
-	rows, err := db.Conn.NamedQueryContext(ctx, query, person)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-	if rows.Next() {
-		if err := rows.Scan(&person.CreatedAt, &person.UpdatedAt); err != nil {
+	return db.scoped(ctx, func(q queryer) error {
+		rows, err := sqlx.NamedQueryContext(ctx, q, insertPersonQuery, newPersonRecord(ctx, person))
+		if err != nil {
 			return err
 		}
-	}
-	return nil
+		defer rows.Close()
+		if rows.Next() {
+			if err := rows.Scan(&person.CreatedAt, &person.UpdatedAt); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
 }
 
 func (db *DB) GetPerson(ctx context.Context, id uuid.UUID) (*model.Person, error) {
 	var person model.Person
-	err := db.Conn.GetContext(ctx, &person, `SELECT * FROM persons WHERE id=$1`, id)
+	err := db.scoped(ctx, func(q queryer) error {
+		return q.GetContext(ctx, &person, `SELECT `+personColumns+` FROM persons WHERE id=$1 AND tenant_id=$2`,
+			id, tenant.FromContext(ctx))
+	})
 	if err != nil {
-		if errors.Is(err, ErrNotFound) {
+		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
 		}
 		return nil, err
@@ -43,81 +103,329 @@ func (db *DB) GetPerson(ctx context.Context, id uuid.UUID) (*model.Person, error
 }
 
 func (db *DB) UpdatePerson(ctx context.Context, person *model.Person) error {
-	query := `
-        UPDATE persons SET name=:name, surname=:surname, patronymic=:patronymic, age=:age, gender=:gender, nationality=:nationality, updated_at=NOW()
-		WHERE id=:id
-		RETURNING updated_at
-	`
-	rows, err := db.Conn.NamedQueryContext(ctx, query, person)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-	if rows.Next() {
-		if err := rows.Scan(&person.UpdatedAt); err != nil {
+	return db.scoped(ctx, func(q queryer) error {
+		rows, err := sqlx.NamedQueryContext(ctx, q, updatePersonQuery, newPersonRecord(ctx, person))
+		if err != nil {
 			return err
 		}
+		defer rows.Close()
+		if rows.Next() {
+			if err := rows.Scan(&person.UpdatedAt); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// ModifyPerson блокирует запись арендатора из ctx, передает ее fn и сохраняет изменения
+// в той же транзакции, чтобы параллельные изменения не перезаписывали друг друга.
+// Ошибка fn откатывает транзакцию и возвращается без изменений.
+func (db *DB) ModifyPerson(ctx context.Context, id uuid.UUID, fn func(person *model.Person) error) (*model.Person, error) {
+	tx, err := db.begin(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	defer tx.Rollback()
+
+	var person model.Person
+	err = tx.GetContext(ctx, &person, `SELECT `+personColumns+` FROM persons WHERE id=$1 AND tenant_id=$2 FOR UPDATE`,
+		id, tenant.FromContext(ctx))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(&person); err != nil {
+		return nil, err
+	}
+	query, args, err := tx.BindNamed(updatePersonQuery, newPersonRecord(ctx, &person))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&person.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &person, nil
 }
 
 func (db *DB) DeletePerson(ctx context.Context, id uuid.UUID) error {
-	res, err := db.Conn.ExecContext(ctx, `DELETE FROM persons WHERE id=$1`, id)
+	var rows int64
+	err := db.scoped(ctx, func(q queryer) error {
+		res, err := q.ExecContext(ctx, deletePersonQuery, id, tenant.FromContext(ctx), "deleted")
+		if err != nil {
+			return err
+		}
+		rows, _ = res.RowsAffected()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	rows, _ := res.RowsAffected()
 	if rows == 0 {
 		return ErrNotFound
 	}
 	return nil
 }
 
-func (db *DB) ListPersons(ctx context.Context, filter map[string]interface{}, limit, offset int) ([]*model.Person, error) {
-	query := `SELECT * FROM persons WHERE 1=1`
-	args := map[string]interface{}{}
+// ListOptions задает параметры выборки списка людей.
+// Если указан Cursor, Offset игнорируется; пустой Sort означает DefaultSort.
+type ListOptions struct {
+	Filter map[string]interface{}
+	// Expr - дополнительное условие на языке выражений фильтра
+	Expr filter.Expr
+	// Search включает нечеткий и фонетический поиск по имени и фамилии;
+	// результаты упорядочиваются по убыванию релевантности, Sort и Cursor не поддерживаются
+	Search string
+	Sort   []SortKey
+	Limit  int
+	Offset int
+	Cursor *Cursor
+}
+
+// PersonPage представляет страницу списка людей с курсорами соседних страниц
+type PersonPage struct {
+	Persons    []*model.Person
+	HasNext    bool
+	HasPrev    bool
+	NextCursor *Cursor
+	PrevCursor *Cursor
+}
+
+// personFilter строит условие WHERE и именованные параметры по фильтру списка
+// среди записей арендатора из ctx
+func personFilter(ctx context.Context, opts ListOptions) (string, map[string]interface{}) {
+	where := "tenant_id = :tenant_id"
+	args := map[string]interface{}{"tenant_id": tenant.FromContext(ctx)}
 
-	if v, ok := filter["name"]; ok {
-		query += " AND name ILIKE :name"
+	if v, ok := opts.Filter["name"]; ok {
+		where += " AND name ILIKE :name"
 		args["name"] = "%" + v.(string) + "%"
 	}
-	if v, ok := filter["surname"]; ok {
-		query += " AND surname ILIKE :surname"
+	if v, ok := opts.Filter["surname"]; ok {
+		where += " AND surname ILIKE :surname"
 		args["surname"] = "%" + v.(string) + "%"
 	}
-	if v, ok := filter["gender"]; ok {
-		query += " AND gender = :gender"
+	if v, ok := opts.Filter["gender"]; ok {
+		where += " AND gender = :gender"
 		args["gender"] = v
 	}
-	if v, ok := filter["nationality"]; ok {
-		query += " AND nationality = :nationality"
+	if v, ok := opts.Filter["nationality"]; ok {
+		where += " AND nationality = :nationality"
 		args["nationality"] = v
 	}
-	if v, ok := filter["age_min"]; ok {
-		query += " AND age >= :age_min"
+	if v, ok := opts.Filter["age_min"]; ok {
+		where += " AND age >= :age_min"
 		args["age_min"] = v
 	}
-	if v, ok := filter["age_max"]; ok {
-		query += " AND age <= :age_max"
+	if v, ok := opts.Filter["age_max"]; ok {
+		where += " AND age <= :age_max"
 		args["age_max"] = v
 	}
-	query += " ORDER BY created_at DESC LIMIT :limit OFFSET :offset"
-	args["limit"] = limit
-	args["offset"] = offset
+	if opts.Expr != nil {
+		where += " AND " + filter.Compile(opts.Expr, "expr_", args)
+	}
+	if opts.Search != "" {
+		where += ` AND (name % :search OR surname % :search OR (name || ' ' || surname) % :search
+			OR name_phonetic = ANY(:search_keys) OR surname_phonetic = ANY(:search_keys))`
+		args["search"] = opts.Search
+		args["search_keys"] = phonetic.Keys(opts.Search)
+	}
+	return where, args
+}
+
+func (db *DB) ListPersons(ctx context.Context, opts ListOptions) (*PersonPage, error) {
+	where, args := personFilter(ctx, opts)
+	if opts.Search != "" {
+		return db.searchPersons(ctx, opts, where, args)
+	}
+	query := `SELECT ` + personColumns + ` FROM persons WHERE ` + where
+
+	keys := opts.Sort
+	if len(keys) == 0 {
+		keys = DefaultSort
+	}
+	if err := validateSort(keys); err != nil {
+		return nil, err
+	}
+	cursor := opts.Cursor
+	if cursor != nil && (cursor.Sort != sortSpec(keys) || len(cursor.Values) != len(keys) || !validCursorValues(keys, cursor.Values)) {
+		return nil, ErrInvalidCursor
+	}
+	backward := cursor != nil && cursor.Backward
+	if cursor != nil {
+		query += " AND " + cursorCondition(keys, cursor, args)
+	}
+	query += " ORDER BY " + orderBy(keys, backward) + " LIMIT :limit"
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	args["limit"] = opts.Limit + 1
+	if cursor == nil {
+		query += " OFFSET :offset"
+		args["offset"] = opts.Offset
+	}
+
+	result, err := db.queryPersons(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(result) > opts.Limit
+	if hasMore {
+		result = result[:opts.Limit]
+	}
+	page := &PersonPage{Persons: result}
+	if len(result) == 0 {
+		return page, nil
+	}
+	if backward {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
 
-	rows, err := db.Conn.NamedQueryContext(ctx, query, args)
+	first, last := result[0], result[len(result)-1]
+	hasNext := hasMore || backward
+	hasPrev := (backward && hasMore) || (!backward && (cursor != nil || opts.Offset > 0))
+	page.HasNext, page.HasPrev = hasNext, hasPrev
+	if hasNext {
+		page.NextCursor = cursorAt(keys, last, false)
+	}
+	if hasPrev {
+		page.PrevCursor = cursorAt(keys, first, true)
+	}
+	return page, nil
+}
+
+// searchScore оценивает релевантность записи поисковому запросу: максимальное
+// триграммное сходство с именем, фамилией и их сочетаниями плюс бонус
+// за совпадение фонетического ключа
+const searchScore = `CAST(LEAST(1.0, GREATEST(
+		similarity(name, :search), similarity(surname, :search),
+		similarity(name || ' ' || surname, :search), similarity(surname || ' ' || name, :search)
+	) + CASE WHEN name_phonetic = ANY(:search_keys) OR surname_phonetic = ANY(:search_keys) THEN 0.3 ELSE 0 END) AS DOUBLE PRECISION)`
+
+func (db *DB) searchPersons(ctx context.Context, opts ListOptions, where string, args map[string]interface{}) (*PersonPage, error) {
+	if opts.Cursor != nil {
+		return nil, ErrInvalidCursor
+	}
+	query := `SELECT ` + personColumns + `, ` + searchScore + ` AS score FROM persons WHERE ` + where +
+		` ORDER BY score DESC, id ASC LIMIT :limit OFFSET :offset`
+	args["limit"] = opts.Limit + 1
+	args["offset"] = opts.Offset
+
+	result, err := db.queryPersons(ctx, query, args)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	page := &PersonPage{HasNext: len(result) > opts.Limit, HasPrev: opts.Offset > 0}
+	if page.HasNext {
+		result = result[:opts.Limit]
+	}
+	page.Persons = result
+	return page, nil
+}
+
+func (db *DB) queryPersons(ctx context.Context, query string, args map[string]interface{}) ([]*model.Person, error) {
 	var result []*model.Person
-	for rows.Next() {
-		var person model.Person
-		if err := rows.StructScan(&person); err != nil {
-			return nil, err
+	err := db.scoped(ctx, func(q queryer) error {
+		rows, err := sqlx.NamedQueryContext(ctx, q, query, args)
+		if err != nil {
+			return err
 		}
-		result = append(result, &person)
+		defer rows.Close()
+		for rows.Next() {
+			var person model.Person
+			if err := rows.StructScan(&person); err != nil {
+				return err
+			}
+			result = append(result, &person)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 	return result, nil
+}
 
+// BackfillPhonetic заполняет фонетические ключи записей всех арендаторов, созданных до их появления
+func (db *DB) BackfillPhonetic(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		var persons []*model.Person
+		err := db.maintenance(ctx, func(q queryer) error {
+			err := q.SelectContext(ctx, &persons,
+				`SELECT `+personColumns+` FROM persons WHERE name_phonetic IS NULL OR surname_phonetic IS NULL LIMIT 500`)
+			if err != nil {
+				return err
+			}
+			for _, person := range persons {
+				_, err := q.NamedExecContext(ctx,
+					`UPDATE persons SET name_phonetic=:name_phonetic, surname_phonetic=:surname_phonetic WHERE id=:id`,
+					newPersonRecord(ctx, person))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		if len(persons) == 0 {
+			return total, nil
+		}
+		total += len(persons)
+	}
+}
+
+// CountPersons возвращает количество людей, подходящих под фильтр.
+// При estimate=true используется оценка планировщика вместо COUNT(*).
+func (db *DB) CountPersons(ctx context.Context, opts ListOptions, estimate bool) (int64, error) {
+	where, args := personFilter(ctx, opts)
+	if estimate {
+		return db.estimateRows(ctx, `SELECT 1 FROM persons WHERE `+where, args)
+	}
+	query, params, err := db.Conn.BindNamed(`SELECT COUNT(*) FROM persons WHERE `+where, args)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	err = db.scoped(ctx, func(q queryer) error {
+		return q.GetContext(ctx, &total, query, params...)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (db *DB) estimateRows(ctx context.Context, query string, args map[string]interface{}) (int64, error) {
+	query, params, err := db.Conn.BindNamed(`EXPLAIN (FORMAT JSON) `+query, args)
+	if err != nil {
+		return 0, err
+	}
+	var raw []byte
+	err = db.scoped(ctx, func(q queryer) error {
+		return q.GetContext(ctx, &raw, query, params...)
+	})
+	if err != nil {
+		return 0, err
+	}
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return 0, err
+	}
+	if len(plan) == 0 {
+		return 0, nil
+	}
+	return int64(plan[0].Plan.Rows), nil
 }
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryLogger пишет выполненные запросы в журнал; идентификатор запроса API
+// журнал берет из контекста, поэтому запросы к БД связываются с вызвавшим их запросом
+type queryLogger struct {
+	logger *slog.Logger
+}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+func (l *queryLogger) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !l.logger.Enabled(ctx, slog.LevelDebug) {
+		return ctx
+	}
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (l *queryLogger) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	attrs := []any{
+		"sql", q.sql,
+		"rows", data.CommandTag.RowsAffected(),
+		"duration_ms", float64(time.Since(q.start).Microseconds()) / 1000,
+	}
+	if data.Err != nil {
+		attrs = append(attrs, "error", data.Err)
+	}
+	l.logger.DebugContext(ctx, "db query", attrs...)
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// TakeRateLimitToken забирает токен из общего для всех экземпляров сервиса ведра key,
+// которое пополняется со скоростью rate токенов в секунду до burst.
+// Пополнение и списание выполняются одним запросом, поэтому конкурентные вызовы не теряют обновлений.
+// Ключ хранится хешем: субъект из JWT в нем не ограничен по длине.
+func (db *DB) TakeRateLimitToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	sum := sha256.Sum256([]byte(key))
+	var (
+		allowed bool
+		tokens  float64
+	)
+	err := db.Conn.QueryRowContext(ctx, `
+		INSERT INTO rate_limits AS rl (key, tokens, allowed, updated_at)
+		VALUES ($1, CAST($3 AS DOUBLE PRECISION) - 1, true, NOW())
+		ON CONFLICT (key) DO UPDATE SET (tokens, allowed, updated_at) = (
+			SELECT CASE WHEN available >= 1 THEN available - 1 ELSE available END, available >= 1, NOW()
+			FROM (SELECT LEAST(CAST($3 AS DOUBLE PRECISION),
+				rl.tokens + EXTRACT(EPOCH FROM NOW() - rl.updated_at) * CAST($2 AS DOUBLE PRECISION)) AS available) b
+		)
+		RETURNING allowed, tokens
+	`, hex.EncodeToString(sum[:]), rate, burst).Scan(&allowed, &tokens)
+	return allowed, tokens, err
+}
+
+// PurgeRateLimits удаляет ведра, не использовавшиеся дольше idle
+func (db *DB) PurgeRateLimits(ctx context.Context, idle time.Duration) (int64, error) {
+	res, err := db.Conn.ExecContext(ctx, `
+		DELETE FROM rate_limits WHERE updated_at < NOW() - CAST($1 AS BIGINT) * INTERVAL '1 millisecond'
+	`, idle.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
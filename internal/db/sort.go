@@ -0,0 +1,212 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+var ErrInvalidSort = errors.New("invalid sort")
+
+// SortKey задает поле сортировки списка и ее направление
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// DefaultSort используется, если сортировка не задана
+var DefaultSort = []SortKey{{Field: "created_at", Desc: true}}
+
+type sortColumn struct {
+	sqlType  string
+	nullable bool
+	value    func(p *model.Person) *string
+}
+
+// sortColumns перечисляет поля, по которым разрешена сортировка
+var sortColumns = map[string]sortColumn{
+	"name":    {sqlType: "TEXT", value: func(p *model.Person) *string { return &p.Name }},
+	"surname": {sqlType: "TEXT", value: func(p *model.Person) *string { return &p.Surname }},
+	"age": {sqlType: "INT", nullable: true, value: func(p *model.Person) *string {
+		if p.Age == nil {
+			return nil
+		}
+		v := strconv.Itoa(*p.Age)
+		return &v
+	}},
+	"gender":      {sqlType: "TEXT", nullable: true, value: func(p *model.Person) *string { return p.Gender }},
+	"nationality": {sqlType: "TEXT", nullable: true, value: func(p *model.Person) *string { return p.Nationality }},
+	"created_at": {sqlType: "TIMESTAMPTZ", value: func(p *model.Person) *string {
+		v := p.CreatedAt.Format(time.RFC3339Nano)
+		return &v
+	}},
+	"updated_at": {sqlType: "TIMESTAMPTZ", value: func(p *model.Person) *string {
+		v := p.UpdatedAt.Format(time.RFC3339Nano)
+		return &v
+	}},
+}
+
+// validCursorValues проверяет, что значения курсора приводятся к типам
+// колонок сортировки, чтобы подделанный курсор не доходил до CAST в запросе
+func validCursorValues(keys []SortKey, values []*string) bool {
+	for i, k := range keys {
+		col := sortColumns[k.Field]
+		v := values[i]
+		if v == nil {
+			if !col.nullable {
+				return false
+			}
+			continue
+		}
+		switch col.sqlType {
+		case "INT":
+			if _, err := strconv.ParseInt(*v, 10, 32); err != nil {
+				return false
+			}
+		case "TIMESTAMPTZ":
+			if _, err := time.Parse(time.RFC3339Nano, *v); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func validateSort(keys []SortKey) error {
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if _, ok := sortColumns[k.Field]; !ok {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidSort, k.Field)
+		}
+		if seen[k.Field] {
+			return fmt.Errorf("%w: duplicate field %q", ErrInvalidSort, k.Field)
+		}
+		seen[k.Field] = true
+	}
+	return nil
+}
+
+// sortSpec возвращает каноническую запись сортировки для сверки с курсором
+func sortSpec(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// orderBy строит ORDER BY; id добавляется последним ключом для однозначного порядка.
+// NULL всегда идут в конце списка, при обратном обходе порядок инвертируется.
+func orderBy(keys []SortKey, backward bool) string {
+	parts := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		desc := k.Desc != backward
+		nulls := "NULLS LAST"
+		if backward {
+			nulls = "NULLS FIRST"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", k.Field, direction(desc), nulls))
+	}
+	last := keys[len(keys)-1].Desc != backward
+	parts = append(parts, "id "+direction(last))
+	return strings.Join(parts, ", ")
+}
+
+func direction(desc bool) string {
+	if desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// cursorCondition строит условие, отбирающее записи строго после (или до, при
+// Backward) позиции курсора в порядке keys, и добавляет значения курсора в args
+func cursorCondition(keys []SortKey, cursor *Cursor, args map[string]interface{}) string {
+	args["cursor_id"] = cursor.ID
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		name := fmt.Sprintf("cursor_%d", i)
+		args[name] = cursor.Values[i]
+		params[i] = fmt.Sprintf("CAST(:%s AS %s)", name, sortColumns[k.Field].sqlType)
+	}
+
+	// Для ненулевых колонок с одинаковым направлением достаточно сравнения
+	// кортежей, которое использует индекс
+	simple := true
+	for i, k := range keys {
+		if sortColumns[k.Field].nullable || k.Desc != keys[0].Desc || cursor.Values[i] == nil {
+			simple = false
+		}
+	}
+	if simple {
+		cols := make([]string, len(keys))
+		for i, k := range keys {
+			cols[i] = k.Field
+		}
+		op := "<"
+		if keys[0].Desc == cursor.Backward {
+			op = ">"
+		}
+		return fmt.Sprintf("(%s, id) %s (%s, :cursor_id)", strings.Join(cols, ", "), op, strings.Join(params, ", "))
+	}
+
+	var or []string
+	var eq []string
+	for i, k := range keys {
+		cond := beyond(k, params[i], cursor.Values[i] == nil, cursor.Backward)
+		if cond != "" {
+			or = append(or, "("+strings.Join(append(eq[:len(eq):len(eq)], cond), " AND ")+")")
+		}
+		if cursor.Values[i] == nil {
+			eq = append(eq, k.Field+" IS NULL")
+		} else {
+			eq = append(eq, fmt.Sprintf("%s = %s", k.Field, params[i]))
+		}
+	}
+	idOp := "<"
+	if keys[len(keys)-1].Desc == cursor.Backward {
+		idOp = ">"
+	}
+	or = append(or, "("+strings.Join(append(eq, "id "+idOp+" :cursor_id"), " AND ")+")")
+	return "(" + strings.Join(or, " OR ") + ")"
+}
+
+// beyond возвращает условие "значение ключа строго дальше курсора" с учетом
+// того, что NULL находятся в конце списка; пустая строка означает, что таких значений нет
+func beyond(k SortKey, param string, isNull, backward bool) string {
+	op := ">"
+	if k.Desc {
+		op = "<"
+	}
+	if backward {
+		if isNull {
+			return k.Field + " IS NOT NULL"
+		}
+		if op == ">" {
+			op = "<"
+		} else {
+			op = ">"
+		}
+		return fmt.Sprintf("%s %s %s", k.Field, op, param)
+	}
+	if isNull {
+		return ""
+	}
+	return fmt.Sprintf("(%s %s %s OR %s IS NULL)", k.Field, op, param, k.Field)
+}
+
+// cursorAt возвращает курсор, указывающий на запись p
+func cursorAt(keys []SortKey, p *model.Person, backward bool) *Cursor {
+	values := make([]*string, len(keys))
+	for i, k := range keys {
+		values[i] = sortColumns[k.Field].value(p)
+	}
+	return &Cursor{Sort: sortSpec(keys), Values: values, ID: p.ID, Backward: backward}
+}
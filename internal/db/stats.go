@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"slices"
+
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// statsRow - строка агрегации по наборам группировки; признаки grouping_*
+// равны 1, если поле не входит в набор группировки строки
+type statsRow struct {
+	Gender        *string `db:"gender"`
+	Nationality   *string `db:"nationality"`
+	AgeBucket     *int    `db:"age_bucket"`
+	Count         int64   `db:"count"`
+	GroupedGender int     `db:"grouping_gender"`
+	GroupedNation int     `db:"grouping_nationality"`
+	GroupedAge    int     `db:"grouping_age"`
+}
+
+// PersonStats считает распределения людей, подходящих под фильтр opts, по полу,
+// национальности и возрастным интервалам шириной bucketWidth, полноту обогащения
+// и таблицу пола по национальностям. Limit, Offset, Sort и Cursor игнорируются.
+func (db *DB) PersonStats(ctx context.Context, opts ListOptions, bucketWidth int) (*model.Stats, error) {
+	where, args := personFilter(ctx, opts)
+	args["bucket_width"] = bucketWidth
+
+	stats := &model.Stats{
+		ByGender:            []model.StatsBucket{},
+		ByNationality:       []model.StatsBucket{},
+		ByAge:               []model.AgeBucket{},
+		GenderByNationality: []model.CrossTabCell{},
+	}
+	query, params, err := db.Conn.BindNamed(`
+		SELECT
+			COUNT(*) AS total,
+			COUNT(age) AS with_age,
+			COUNT(gender) AS with_gender,
+			COUNT(nationality) AS with_nationality,
+			COUNT(*) FILTER (WHERE age IS NOT NULL AND gender IS NOT NULL AND nationality IS NOT NULL) AS complete,
+			COUNT(*) FILTER (WHERE age IS NULL AND gender IS NULL AND nationality IS NULL) AS empty
+		FROM persons WHERE `+where, args)
+	if err != nil {
+		return nil, err
+	}
+	groupQuery, groupParams, err := db.Conn.BindNamed(`
+		SELECT gender, nationality, age_bucket, COUNT(*) AS count,
+			GROUPING(gender) AS grouping_gender,
+			GROUPING(nationality) AS grouping_nationality,
+			GROUPING(age_bucket) AS grouping_age
+		FROM (
+			SELECT gender, nationality, (age / CAST(:bucket_width AS INT)) * CAST(:bucket_width AS INT) AS age_bucket
+			FROM persons WHERE `+where+`
+		) p
+		GROUP BY GROUPING SETS ((gender), (nationality), (age_bucket), (nationality, gender))
+		ORDER BY count DESC, nationality, gender, age_bucket
+	`, args)
+	if err != nil {
+		return nil, err
+	}
+	c := &stats.Completeness
+	var rows []statsRow
+	err = db.scoped(ctx, func(q queryer) error {
+		err := q.QueryRowxContext(ctx, query, params...).Scan(&stats.Total,
+			&c.WithAge, &c.WithGender, &c.WithNationality, &c.Complete, &c.Empty)
+		if err != nil {
+			return err
+		}
+		return q.SelectContext(ctx, &rows, groupQuery, groupParams...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		switch {
+		case row.GroupedGender == 0 && row.GroupedNation == 0:
+			stats.GenderByNationality = append(stats.GenderByNationality,
+				model.CrossTabCell{Nationality: row.Nationality, Gender: row.Gender, Count: row.Count})
+		case row.GroupedGender == 0:
+			stats.ByGender = append(stats.ByGender, model.StatsBucket{Key: row.Gender, Count: row.Count})
+		case row.GroupedNation == 0:
+			stats.ByNationality = append(stats.ByNationality, model.StatsBucket{Key: row.Nationality, Count: row.Count})
+		case row.GroupedAge == 0:
+			bucket := model.AgeBucket{Count: row.Count}
+			if row.AgeBucket != nil {
+				from, to := *row.AgeBucket, *row.AgeBucket+bucketWidth-1
+				bucket.From, bucket.To = &from, &to
+			}
+			stats.ByAge = append(stats.ByAge, bucket)
+		}
+	}
+	// Возрастные интервалы удобнее читать по возрастанию, неизвестный возраст - в конце
+	sortAgeBuckets(stats.ByAge)
+	return stats, nil
+}
+
+func sortAgeBuckets(buckets []model.AgeBucket) {
+	slices.SortFunc(buckets, func(a, b model.AgeBucket) int {
+		switch {
+		case a.From == nil && b.From == nil:
+			return 0
+		case a.From == nil:
+			return 1
+		case b.From == nil:
+			return -1
+		default:
+			return *a.From - *b.From
+		}
+	})
+}
+
+// CountPersonsByTenant считает записи каждого арендатора для метрик
+func (db *DB) CountPersonsByTenant(ctx context.Context) (map[string]int, error) {
+	var rows []struct {
+		Tenant string `db:"tenant_id"`
+		Count  int    `db:"count"`
+	}
+	err := db.maintenance(ctx, func(q queryer) error {
+		return q.SelectContext(ctx, &rows, `SELECT tenant_id, COUNT(*) AS count FROM persons GROUP BY tenant_id`)
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Tenant] = row.Count
+	}
+	return counts, nil
+}
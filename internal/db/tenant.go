@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+)
+
+var ErrQuotaExceeded = errors.New("enrichment quota exceeded")
+
+// tenantTables - таблицы с колонкой tenant_id и политикой tenant_isolation;
+// import_errors проверяется через imports
+var tenantTables = []string{"persons", "imports", "import_errors", "person_merges", "person_events", "enrichment_outcomes"}
+
+// queryer - общие методы *sqlx.DB и *sqlx.Tx, через которые выполняются запросы арендатора
+type queryer interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// SetRowLevelSecurity включает или отключает политики RLS таблиц арендаторов.
+// Политики применяются и к владельцу таблиц (FORCE), под которым работает сервис.
+func (db *DB) SetRowLevelSecurity(ctx context.Context, enabled bool) error {
+	action := "DISABLE ROW LEVEL SECURITY, NO FORCE ROW LEVEL SECURITY"
+	if enabled {
+		action = "ENABLE ROW LEVEL SECURITY, FORCE ROW LEVEL SECURITY"
+	}
+	for _, table := range tenantTables {
+		if _, err := db.Conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s %s", table, action)); err != nil {
+			return err
+		}
+	}
+	db.RowLevelSecurity = enabled
+	return nil
+}
+
+// begin начинает транзакцию арендатора из ctx; с RLS арендатор передается в app.tenant_id до конца транзакции
+func (db *DB) begin(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	tx, err := db.Conn.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if db.RowLevelSecurity {
+		if _, err := tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true)`, tenant.FromContext(ctx)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+// scoped выполняет fn с запросами арендатора из ctx. Без RLS запросы идут напрямую через пул,
+// с RLS - в транзакции, где установлен app.tenant_id.
+func (db *DB) scoped(ctx context.Context, fn func(q queryer) error) error {
+	if !db.RowLevelSecurity {
+		return fn(db.Conn)
+	}
+	tx, err := db.begin(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// maintenance выполняет fn с доступом ко всем арендаторам, например для заполнения новых колонок
+func (db *DB) maintenance(ctx context.Context, fn func(q queryer) error) error {
+	if !db.RowLevelSecurity {
+		return fn(db.Conn)
+	}
+	tx, err := db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('app.maintenance', 'on', true)`); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeEnrichmentQuota списывает n обогащений из суточной квоты арендатора из ctx.
+// Квота задается в tenant_quotas (NULL - без ограничения) или DailyEnrichmentQuota по умолчанию.
+// Если квоты не хватает, ничего не списывается и возвращается ErrQuotaExceeded.
+// Сутки считаются по UTC.
+func (db *DB) ConsumeEnrichmentQuota(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	// При конфликте upsert блокирует строку использования и перепроверяет условие,
+	// поэтому параллельные списания не превышают квоту
+	var used int
+	err := db.Conn.QueryRowxContext(ctx, `
+		WITH quota AS (
+			SELECT CASE WHEN t.tenant_id IS NULL THEN CAST($3 AS INT) ELSE t.daily_enrichments END AS daily
+			FROM (SELECT 1) d LEFT JOIN tenant_quotas t ON t.tenant_id = $1
+		)
+		INSERT INTO enrichment_usage AS u (tenant_id, day, used)
+		SELECT $1, CAST($2 AS DATE), $4 FROM quota WHERE daily IS NULL OR $4 <= daily
+		ON CONFLICT (tenant_id, day) DO UPDATE SET used = u.used + EXCLUDED.used
+		WHERE (SELECT daily FROM quota) IS NULL OR u.used + EXCLUDED.used <= (SELECT daily FROM quota)
+		RETURNING u.used
+	`, tenant.FromContext(ctx), today(), db.defaultQuota(), n).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrQuotaExceeded
+	}
+	return err
+}
+
+// defaultQuota возвращает квоту по умолчанию или nil, если она не ограничена
+func (db *DB) defaultQuota() *int {
+	if db.DailyEnrichmentQuota <= 0 {
+		return nil
+	}
+	return &db.DailyEnrichmentQuota
+}
+
+// TenantQuota - квота и использование обогащений арендатора за текущие сутки
+type TenantQuota struct {
+	Tenant string `db:"tenant_id"`
+	// Daily - суточная квота, nil - без ограничения
+	Daily *int `db:"daily_enrichments"`
+	// Custom сообщает, что квота задана для арендатора, а не взята по умолчанию
+	Custom bool `db:"custom"`
+	Used   int  `db:"used"`
+}
+
+// SetTenantQuota задает суточную квоту арендатора; nil снимает ограничение
+func (db *DB) SetTenantQuota(ctx context.Context, tenantID string, daily *int) error {
+	_, err := db.Conn.ExecContext(ctx, `
+		INSERT INTO tenant_quotas (tenant_id, daily_enrichments) VALUES ($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE SET daily_enrichments = EXCLUDED.daily_enrichments
+	`, tenantID, daily)
+	return err
+}
+
+// ResetTenantQuota возвращает арендатору квоту по умолчанию
+func (db *DB) ResetTenantQuota(ctx context.Context, tenantID string) error {
+	res, err := db.Conn.ExecContext(ctx, `DELETE FROM tenant_quotas WHERE tenant_id=$1`, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TenantQuotas возвращает арендаторов с собственной квотой или обогащениями за текущие сутки
+func (db *DB) TenantQuotas(ctx context.Context) ([]TenantQuota, error) {
+	var quotas []TenantQuota
+	err := db.Conn.SelectContext(ctx, &quotas, `
+		SELECT COALESCE(q.tenant_id, u.tenant_id) AS tenant_id,
+			CASE WHEN q.tenant_id IS NULL THEN CAST($2 AS INT) ELSE q.daily_enrichments END AS daily_enrichments,
+			q.tenant_id IS NOT NULL AS custom,
+			COALESCE(u.used, 0) AS used
+		FROM tenant_quotas q
+		FULL JOIN (SELECT tenant_id, used FROM enrichment_usage WHERE day = CAST($1 AS DATE)) u ON u.tenant_id = q.tenant_id
+		ORDER BY 1
+	`, today(), db.defaultQuota())
+	return quotas, err
+}
+
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/tenant"
+)
+
+var ErrInvalidInterval = errors.New("invalid interval")
+
+// maxTimeSeriesPoints ограничивает количество интервалов в одном ответе
+const maxTimeSeriesPoints = 1000
+
+// outcomeRecord дополняет результат обогащения арендатором
+type outcomeRecord struct {
+	model.EnrichmentOutcome
+	TenantID string `db:"tenant_id"`
+}
+
+// RecordEnrichmentOutcomes сохраняет результаты обращений к провайдерам обогащения для арендатора из ctx
+func (db *DB) RecordEnrichmentOutcomes(ctx context.Context, outcomes []model.EnrichmentOutcome) error {
+	if len(outcomes) == 0 {
+		return nil
+	}
+	records := make([]outcomeRecord, len(outcomes))
+	for i, o := range outcomes {
+		records[i] = outcomeRecord{EnrichmentOutcome: o, TenantID: tenant.FromContext(ctx)}
+	}
+	return db.scoped(ctx, func(q queryer) error {
+		_, err := q.NamedExecContext(ctx, `
+			INSERT INTO enrichment_outcomes (tenant_id, provider, success, error, duration_ms, occurred_at)
+			VALUES (:tenant_id, :provider, :success, :error, :duration_ms, :occurred_at)
+		`, records)
+		return err
+	})
+}
+
+// truncate округляет t вниз до начала интервала в UTC; недели начинаются с понедельника, как в date_trunc
+func truncate(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func step(t time.Time, interval string) time.Time {
+	switch interval {
+	case "hour":
+		return t.Add(time.Hour)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// TimeSeries считает для арендатора из ctx по интервалам hour, day или week в диапазоне [from, to)
+// созданные, измененные и удаленные записи и результаты обращений к провайдерам.
+// Интервалы без событий возвращаются с нулевыми значениями.
+func (db *DB) TimeSeries(ctx context.Context, interval string, from, to time.Time) (*model.TimeSeries, error) {
+	if interval != "hour" && interval != "day" && interval != "week" {
+		return nil, fmt.Errorf("%w: must be hour, day or week", ErrInvalidInterval)
+	}
+	from = truncate(from, interval)
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidInterval)
+	}
+
+	series := &model.TimeSeries{Interval: interval, From: from, To: to.UTC()}
+	index := map[time.Time]*model.TimeSeriesPoint{}
+	for t := from; t.Before(to); t = step(t, interval) {
+		if len(series.Points) == maxTimeSeriesPoints {
+			return nil, fmt.Errorf("%w: range covers more than %d intervals", ErrInvalidInterval, maxTimeSeriesPoints)
+		}
+		series.Points = append(series.Points, model.TimeSeriesPoint{Bucket: t, Enrichment: map[string]model.EnrichmentCounts{}})
+	}
+	for i := range series.Points {
+		index[series.Points[i].Bucket] = &series.Points[i]
+	}
+
+	type countRow struct {
+		Bucket time.Time `db:"bucket"`
+		Count  int64     `db:"count"`
+	}
+	counters := []struct {
+		query string
+		add   func(p *model.TimeSeriesPoint, n int64)
+	}{
+		{`SELECT date_trunc($1, occurred_at AT TIME ZONE 'UTC') AS bucket, COUNT(*) AS count
+			FROM person_events WHERE tenant_id = $4 AND occurred_at >= $2 AND occurred_at < $3 AND kind = 'created' GROUP BY 1`,
+			func(p *model.TimeSeriesPoint, n int64) { p.Created = n }},
+		{`SELECT date_trunc($1, occurred_at AT TIME ZONE 'UTC') AS bucket, COUNT(*) AS count
+			FROM person_events WHERE tenant_id = $4 AND occurred_at >= $2 AND occurred_at < $3 AND kind = 'updated' GROUP BY 1`,
+			func(p *model.TimeSeriesPoint, n int64) { p.Updated = n }},
+		{`SELECT date_trunc($1, occurred_at AT TIME ZONE 'UTC') AS bucket, COUNT(*) AS count
+			FROM person_events WHERE tenant_id = $4 AND occurred_at >= $2 AND occurred_at < $3 AND kind IN ('deleted', 'merged') GROUP BY 1`,
+			func(p *model.TimeSeriesPoint, n int64) { p.Deleted = n }},
+	}
+	var outcomes []struct {
+		Bucket   time.Time `db:"bucket"`
+		Provider string    `db:"provider"`
+		Success  int64     `db:"success"`
+		Failure  int64     `db:"failure"`
+	}
+	tenantID := tenant.FromContext(ctx)
+	err := db.scoped(ctx, func(q queryer) error {
+		for _, c := range counters {
+			var rows []countRow
+			if err := q.SelectContext(ctx, &rows, c.query, interval, from, to, tenantID); err != nil {
+				return err
+			}
+			for _, row := range rows {
+				if p, ok := index[row.Bucket.UTC()]; ok {
+					c.add(p, row.Count)
+				}
+			}
+		}
+		return q.SelectContext(ctx, &outcomes, `
+			SELECT date_trunc($1, occurred_at AT TIME ZONE 'UTC') AS bucket, provider,
+				COUNT(*) FILTER (WHERE success) AS success,
+				COUNT(*) FILTER (WHERE NOT success) AS failure
+			FROM enrichment_outcomes
+			WHERE tenant_id = $4 AND occurred_at >= $2 AND occurred_at < $3
+			GROUP BY 1, 2
+		`, interval, from, to, tenantID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range outcomes {
+		if p, ok := index[row.Bucket.UTC()]; ok {
+			p.Enrichment[row.Provider] = model.EnrichmentCounts{Success: row.Success, Failure: row.Failure}
+		}
+	}
+	return series, nil
+}
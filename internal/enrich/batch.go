@@ -0,0 +1,156 @@
+package enrich
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// batchSize - максимальное количество имен в одном запросе к провайдерам
+const batchSize = 10
+
+// fetchBatch запрашивает у провайдера данные для группы имен параметрами name[]
+// и декодирует массив ответов, упорядоченный так же, как имена
+func (e *Enricher) fetchBatch(ctx context.Context, provider, baseURL string, names []string, out interface{}) error {
+	return e.fetch(ctx, provider, baseURL, url.Values{"name[]": names}, out)
+}
+
+// chunks разбивает имена на группы не больше batchSize
+func chunks(names []string) [][]string {
+	var out [][]string
+	for len(names) > batchSize {
+		out = append(out, names[:batchSize])
+		names = names[batchSize:]
+	}
+	if len(names) > 0 {
+		out = append(out, names)
+	}
+	return out
+}
+
+func (e *Enricher) GetAgeBatch(ctx context.Context, names []string) (map[string]*int, error) {
+	result := make(map[string]*int, len(names))
+	for _, chunk := range chunks(names) {
+		var resp []AgifyResponse
+		if err := e.fetchBatch(ctx, ProviderAgify, "https://api.agify.io/", chunk, &resp); err != nil {
+			e.Logger.WarnContext(ctx, "failed to get ages", "provider", ProviderAgify, "error", err)
+			return result, err
+		}
+		for i, r := range resp {
+			if i < len(chunk) {
+				result[chunk[i]] = r.Age
+			}
+		}
+	}
+	return result, nil
+}
+
+func (e *Enricher) GetGenderBatch(ctx context.Context, names []string) (map[string]*string, error) {
+	result := make(map[string]*string, len(names))
+	for _, chunk := range chunks(names) {
+		var resp []GenderizeResponse
+		if err := e.fetchBatch(ctx, ProviderGenderize, "https://api.genderize.io/", chunk, &resp); err != nil {
+			e.Logger.WarnContext(ctx, "failed to get genders", "provider", ProviderGenderize, "error", err)
+			return result, err
+		}
+		for i, r := range resp {
+			if i < len(chunk) {
+				result[chunk[i]] = r.Gender
+			}
+		}
+	}
+	return result, nil
+}
+
+func (e *Enricher) GetNationalityBatch(ctx context.Context, names []string) (map[string]*string, error) {
+	result := make(map[string]*string, len(names))
+	for _, chunk := range chunks(names) {
+		var resp []NationalizeResponse
+		if err := e.fetchBatch(ctx, ProviderNationalize, "https://api.nationalize.io/", chunk, &resp); err != nil {
+			e.Logger.WarnContext(ctx, "failed to get nationalities", "provider", ProviderNationalize, "error", err)
+			return result, err
+		}
+		for i, r := range resp {
+			if i < len(chunk) {
+				result[chunk[i]] = topCountry(r)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Unique возвращает имена без повторов в порядке первого появления:
+// столько имен EnrichBatch запрашивает у провайдеров
+func Unique(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var unique []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// EnrichBatch обогащает группу имен пакетными запросами ко всем провайдерам
+// параллельно. Одинаковые имена запрашиваются один раз. Ошибка провайдера не
+// прерывает обогащение: для имен без данных соответствующие поля остаются пустыми.
+// Вместе с данными возвращаются результаты обращений к каждому провайдеру.
+func (e *Enricher) EnrichBatch(ctx context.Context, names []string) (map[string]*EnrichDate, []model.EnrichmentOutcome) {
+	unique := Unique(names)
+	result := make(map[string]*EnrichDate, len(unique))
+	for _, name := range unique {
+		result[name] = &EnrichDate{}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		ages     map[string]*int
+		gender   map[string]*string
+		nation   map[string]*string
+		outcomes = make([]model.EnrichmentOutcome, 3)
+	)
+	if len(unique) == 0 {
+		return result, nil
+	}
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		ctx, span := startSpan(ctx, ProviderAgify, len(unique))
+		start := time.Now()
+		var err error
+		ages, err = e.GetAgeBatch(ctx, unique)
+		endSpan(span, err)
+		outcomes[0] = newOutcome(ProviderAgify, start, err)
+	}()
+	go func() {
+		defer wg.Done()
+		ctx, span := startSpan(ctx, ProviderGenderize, len(unique))
+		start := time.Now()
+		var err error
+		gender, err = e.GetGenderBatch(ctx, unique)
+		endSpan(span, err)
+		outcomes[1] = newOutcome(ProviderGenderize, start, err)
+	}()
+	go func() {
+		defer wg.Done()
+		ctx, span := startSpan(ctx, ProviderNationalize, len(unique))
+		start := time.Now()
+		var err error
+		nation, err = e.GetNationalityBatch(ctx, unique)
+		endSpan(span, err)
+		outcomes[2] = newOutcome(ProviderNationalize, start, err)
+	}()
+	wg.Wait()
+
+	for name, data := range result {
+		data.Age = ages[name]
+		data.Gender = gender[name]
+		data.Nationality = nation[name]
+	}
+	return result, outcomes
+}
@@ -0,0 +1,130 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Параметры автомата отключения провайдера: после breakerThreshold неудачных обращений подряд
+// провайдер не вызывается breakerCooldown, затем пропускается одно пробное обращение
+const (
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// Состояния автомата отключения провайдера
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// ErrCircuitOpen возвращается вместо обращения к провайдеру, отключенному после серии ошибок
+var ErrCircuitOpen = errors.New("provider is temporarily disabled after repeated failures")
+
+// statusError - ответ провайдера с неуспешным HTTP-статусом
+type statusError struct {
+	url    string
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s responded with status %d", e.url, e.status)
+}
+
+// ProviderStatus - состояние провайдера по последним обращениям к нему
+type ProviderStatus struct {
+	Circuit             string
+	ConsecutiveFailures int
+	LastError           string
+	LastSuccess         time.Time
+	LastFailure         time.Time
+}
+
+// breaker - автомат отключения провайдера. Nil-автомат пропускает все обращения.
+type breaker struct {
+	mu       sync.Mutex
+	status   ProviderStatus
+	openedAt time.Time
+	// probing - пробное обращение после отключения еще не завершилось
+	probing bool
+	// now возвращает текущее время; подменяется в тестах
+	now func() time.Time
+}
+
+func newBreaker() *breaker {
+	return &breaker{now: time.Now}
+}
+
+// allow сообщает, можно ли обратиться к провайдеру
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.status.ConsecutiveFailures < breakerThreshold {
+		return true
+	}
+	if b.probing || b.now().Sub(b.openedAt) < breakerCooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record учитывает результат обращения. Отмена запроса клиентом и ответы 4xx на сами имена,
+// кроме 429, не считаются отказом провайдера.
+func (b *breaker) record(ctx context.Context, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	var statusErr *statusError
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return
+	case err == nil, errors.As(err, &statusErr) && statusErr.status < http.StatusInternalServerError &&
+		statusErr.status != http.StatusTooManyRequests:
+		b.status.ConsecutiveFailures = 0
+		b.status.LastSuccess = b.now()
+	default:
+		b.status.ConsecutiveFailures++
+		b.status.LastError = err.Error()
+		b.status.LastFailure = b.now()
+		if b.status.ConsecutiveFailures >= breakerThreshold {
+			b.openedAt = b.status.LastFailure
+		}
+	}
+}
+
+// state возвращает состояние провайдера
+func (b *breaker) state() ProviderStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := b.status
+	switch {
+	case status.ConsecutiveFailures < breakerThreshold:
+		status.Circuit = CircuitClosed
+	case b.probing || b.now().Sub(b.openedAt) >= breakerCooldown:
+		status.Circuit = CircuitHalfOpen
+	default:
+		status.Circuit = CircuitOpen
+	}
+	return status
+}
+
+// Providers возвращает состояние каждого провайдера
+func (e *Enricher) Providers() map[string]ProviderStatus {
+	providers := make(map[string]ProviderStatus, len(e.breakers))
+	for provider, b := range e.breakers {
+		providers[provider] = b.state()
+	}
+	return providers
+}
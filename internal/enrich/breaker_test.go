@@ -0,0 +1,112 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newTestBreaker создает автомат с часами, которыми управляет тест
+func newTestBreaker() (*breaker, *time.Time) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	b := newBreaker()
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	b, now := newTestBreaker()
+	fail := errors.New("connection refused")
+
+	for i := 1; i < breakerThreshold; i++ {
+		b.record(ctx, fail)
+		if !b.allow() || b.state().Circuit != CircuitClosed {
+			t.Fatalf("circuit opened after %d failures", i)
+		}
+	}
+	b.record(ctx, fail)
+	if b.allow() {
+		t.Fatalf("circuit closed after %d failures", breakerThreshold)
+	}
+	if state := b.state(); state.Circuit != CircuitOpen || state.ConsecutiveFailures != breakerThreshold || state.LastError != fail.Error() {
+		t.Fatalf("state = %+v", state)
+	}
+
+	*now = now.Add(breakerCooldown - time.Second)
+	if b.allow() || b.state().Circuit != CircuitOpen {
+		t.Fatal("circuit half-opened before cooldown")
+	}
+
+	// После паузы пропускается ровно одно пробное обращение
+	*now = now.Add(time.Second)
+	if b.state().Circuit != CircuitHalfOpen {
+		t.Fatalf("circuit = %s after cooldown, want %s", b.state().Circuit, CircuitHalfOpen)
+	}
+	if !b.allow() {
+		t.Fatal("probe denied after cooldown")
+	}
+	if b.allow() {
+		t.Fatal("second request allowed while probing")
+	}
+
+	// Неудачная проба снова отключает провайдер на время паузы
+	b.record(ctx, fail)
+	if b.allow() || b.state().Circuit != CircuitOpen {
+		t.Fatal("circuit not reopened after failed probe")
+	}
+
+	*now = now.Add(breakerCooldown)
+	if !b.allow() {
+		t.Fatal("probe denied after second cooldown")
+	}
+	b.record(ctx, nil)
+	if state := b.state(); state.Circuit != CircuitClosed || state.ConsecutiveFailures != 0 || !state.LastSuccess.Equal(*now) {
+		t.Fatalf("state after successful probe = %+v", state)
+	}
+}
+
+func TestBreakerIgnoredErrors(t *testing.T) {
+	b, _ := newTestBreaker()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+	}{
+		{"client canceled", canceled, context.Canceled},
+		{"bad name", context.Background(), &statusError{url: "https://api.agify.io/", status: http.StatusUnprocessableEntity}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < breakerThreshold; i++ {
+				b.record(tt.ctx, tt.err)
+			}
+			if state := b.state(); state.Circuit != CircuitClosed || state.ConsecutiveFailures != 0 {
+				t.Fatalf("state = %+v", state)
+			}
+		})
+	}
+
+	// 429 и 5xx - отказы провайдера
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusBadGateway} {
+		b, _ := newTestBreaker()
+		for i := 0; i < breakerThreshold; i++ {
+			b.record(context.Background(), &statusError{url: "https://api.agify.io/", status: status})
+		}
+		if b.state().Circuit != CircuitOpen {
+			t.Errorf("status %d: circuit = %s, want %s", status, b.state().Circuit, CircuitOpen)
+		}
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *breaker
+	b.record(context.Background(), errors.New("fail"))
+	if !b.allow() {
+		t.Fatal("nil breaker denied a request")
+	}
+}
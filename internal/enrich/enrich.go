@@ -3,10 +3,18 @@ package enrich
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/logging"
+	"github.com/shenikar/Name-analyzer/internal/metrics"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type AgifyResponse struct {
@@ -30,96 +38,192 @@ type NationalizeResponse struct {
 	} `json:"country"`
 }
 
-var httpClient = &http.Client{Timeout: 3 * time.Second}
+var tracer = otel.Tracer("github.com/shenikar/Name-analyzer/internal/enrich")
 
-func GetAge(ctx context.Context, name string) (*int, error) {
-	url := fmt.Sprintf("https://api.agify.io/?name=%s", name)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		log.Printf("Error getting age from agify: %v", err)
-		return nil, err
+// Enricher обогащает данные о людях через внешние API agify, genderize и nationalize
+type Enricher struct {
+	HTTP   *http.Client
+	Logger *slog.Logger
+	// breakers отключают провайдеры после серии ошибок
+	breakers map[string]*breaker
+}
+
+// New создает Enricher с тайм-аутом обращения к провайдеру 3 секунды
+func New(logger *slog.Logger) *Enricher {
+	return &Enricher{
+		HTTP:   &http.Client{Timeout: 3 * time.Second, Transport: requestIDTransport{http.DefaultTransport}},
+		Logger: logger,
+		breakers: map[string]*breaker{
+			ProviderAgify:       newBreaker(),
+			ProviderGenderize:   newBreaker(),
+			ProviderNationalize: newBreaker(),
+		},
 	}
-	defer resp.Body.Close()
-	var agifyResp AgifyResponse
-	if err := json.NewDecoder(resp.Body).Decode(&agifyResp); err != nil {
-		log.Printf("Error decoding agify response: %v", err)
-		return nil, err
+}
+
+// requestIDTransport передает провайдеру идентификатор запроса API в заголовке X-Request-ID
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := logging.RequestID(req.Context())
+	if id == "" {
+		return t.next.RoundTrip(req)
 	}
-	return agifyResp.Age, nil
+	req = req.Clone(req.Context())
+	req.Header.Set(logging.RequestIDHeader, id)
+	return t.next.RoundTrip(req)
 }
 
-func GetGender(ctx context.Context, name string) (*string, error) {
-	url := fmt.Sprintf("https://api.genderize.io/?name=%s", name)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		log.Printf("Error getting gender from genderize: %v", err)
+func (e *Enricher) GetAge(ctx context.Context, name string) (*int, error) {
+	var agify AgifyResponse
+	if err := e.fetch(ctx, ProviderAgify, "https://api.agify.io/", url.Values{"name": {name}}, &agify); err != nil {
+		e.Logger.WarnContext(ctx, "failed to get age", "provider", ProviderAgify, "error", err)
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return agify.Age, nil
+}
+
+func (e *Enricher) GetGender(ctx context.Context, name string) (*string, error) {
 	var genderize GenderizeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&genderize); err != nil {
-		log.Printf("Error decoding genderize response: %v", err)
+	if err := e.fetch(ctx, ProviderGenderize, "https://api.genderize.io/", url.Values{"name": {name}}, &genderize); err != nil {
+		e.Logger.WarnContext(ctx, "failed to get gender", "provider", ProviderGenderize, "error", err)
 		return nil, err
 	}
 	return genderize.Gender, nil
 }
 
-func GetNationality(ctx context.Context, name string) (*string, error) {
-	url := fmt.Sprintf("https://api.nationalize.io/?name=%s", name)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		log.Printf("Error getting nationality from nationalize: %v", err)
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (e *Enricher) GetNationality(ctx context.Context, name string) (*string, error) {
 	var nationalize NationalizeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&nationalize); err != nil {
-		log.Printf("Error decoding genderize response: %v", err)
+	if err := e.fetch(ctx, ProviderNationalize, "https://api.nationalize.io/", url.Values{"name": {name}}, &nationalize); err != nil {
+		e.Logger.WarnContext(ctx, "failed to get nationality", "provider", ProviderNationalize, "error", err)
 		return nil, err
 	}
-	if len(nationalize.Country) > 0 {
-		return &nationalize.Country[0].CountryID, nil
+	return topCountry(nationalize), nil
+}
+
+// topCountry возвращает наиболее вероятную страну из ответа nationalize или nil
+func topCountry(resp NationalizeResponse) *string {
+	if len(resp.Country) > 0 {
+		return &resp.Country[0].CountryID
+	}
+	return nil
+}
+
+// fetch запрашивает у провайдера baseURL с параметрами q и декодирует ответ в out.
+// Длительность и ошибки обращения учитываются в метриках и автомате отключения провайдера;
+// к отключенному провайдеру fetch не обращается и возвращает ErrCircuitOpen.
+func (e *Enricher) fetch(ctx context.Context, provider, baseURL string, q url.Values, out interface{}) (err error) {
+	b := e.breakers[provider]
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	start := time.Now()
+	defer func() {
+		b.record(ctx, err)
+		metrics.ProviderDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.ProviderErrors.WithLabelValues(provider).Inc()
+		}
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := e.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{url: baseURL, status: resp.StatusCode}
 	}
-	return nil, nil
+	return json.NewDecoder(resp.Body).Decode(out)
 }
 
+// Названия провайдеров обогащения
+const (
+	ProviderAgify       = "agify"
+	ProviderGenderize   = "genderize"
+	ProviderNationalize = "nationalize"
+)
+
 type EnrichDate struct {
 	Age         *int
 	Gender      *string
 	Nationality *string
+	// Outcomes - результаты обращений к провайдерам
+	Outcomes []model.EnrichmentOutcome
 }
 
-func EnrichPerson(ctx context.Context, name string) (*EnrichDate, error) {
+// newOutcome фиксирует результат обращения к провайдеру, начатого в start
+func newOutcome(provider string, start time.Time, err error) model.EnrichmentOutcome {
+	outcome := model.EnrichmentOutcome{
+		Provider:   provider,
+		Success:    err == nil,
+		DurationMS: int(time.Since(start).Milliseconds()),
+		OccurredAt: start,
+	}
+	if err != nil {
+		msg := err.Error()
+		outcome.Error = &msg
+	}
+	return outcome
+}
+
+// startSpan начинает span обращения к провайдеру за данными names имен
+func startSpan(ctx context.Context, provider string, names int) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "enrich "+provider,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("enrich.provider", provider), attribute.Int("enrich.names", names)),
+	)
+}
+
+// endSpan завершает span обращения к провайдеру, отмечая ошибку
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (e *Enricher) EnrichPerson(ctx context.Context, name string) (*EnrichDate, error) {
 	type result struct {
 		age         *int
 		gender      *string
 		nationality *string
-		err         error
+		outcome     model.EnrichmentOutcome
 	}
 	ch := make(chan result, 3)
 
 	go func() {
-		age, err := GetAge(ctx, name)
-		ch <- result{age: age, err: err}
+		ctx, span := startSpan(ctx, ProviderAgify, 1)
+		start := time.Now()
+		age, err := e.GetAge(ctx, name)
+		endSpan(span, err)
+		ch <- result{age: age, outcome: newOutcome(ProviderAgify, start, err)}
 	}()
 	go func() {
-		gender, err := GetGender(ctx, name)
-		ch <- result{gender: gender, err: err}
+		ctx, span := startSpan(ctx, ProviderGenderize, 1)
+		start := time.Now()
+		gender, err := e.GetGender(ctx, name)
+		endSpan(span, err)
+		ch <- result{gender: gender, outcome: newOutcome(ProviderGenderize, start, err)}
 	}()
 	go func() {
-		nationality, err := GetNationality(ctx, name)
-		ch <- result{nationality: nationality, err: err}
+		ctx, span := startSpan(ctx, ProviderNationalize, 1)
+		start := time.Now()
+		nationality, err := e.GetNationality(ctx, name)
+		endSpan(span, err)
+		ch <- result{nationality: nationality, outcome: newOutcome(ProviderNationalize, start, err)}
 	}()
 
 	var data EnrichDate
 	for i := 0; i < 3; i++ {
 		res := <-ch
-		if res.err != nil {
-			log.Printf("Error enriching person: %v", res.err)
-		}
+		data.Outcomes = append(data.Outcomes, res.outcome)
 		if res.age != nil {
 			data.Age = res.age
 		}
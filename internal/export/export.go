@@ -0,0 +1,108 @@
+// Package export записывает людей в файлы выгрузки CSV, NDJSON и Parquet
+// по одной записи, не накапливая весь набор в памяти.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Writer записывает людей в выбранном формате. Close дописывает
+// окончание файла и должен вызываться после последней записи.
+type Writer interface {
+	Write(person *model.Person) error
+	Close() error
+}
+
+// NewWriter создает Writer для формата format
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// ContentType возвращает MIME-тип файла выгрузки
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+var csvHeader = []string{"id", "name", "surname", "patronymic", "age", "gender", "nationality", "created_at", "updated_at"}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: cw, record: make([]string, len(csvHeader))}, nil
+}
+
+func (c *csvWriter) Write(p *model.Person) error {
+	c.record[0] = p.ID.String()
+	c.record[1] = p.Name
+	c.record[2] = p.Surname
+	c.record[3] = deref(p.Patronymic)
+	c.record[4] = ""
+	if p.Age != nil {
+		c.record[4] = strconv.Itoa(*p.Age)
+	}
+	c.record[5] = deref(p.Gender)
+	c.record[6] = deref(p.Nationality)
+	c.record[7] = p.CreatedAt.UTC().Format(time.RFC3339Nano)
+	c.record[8] = p.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(p *model.Person) error {
+	return n.enc.Encode(p)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
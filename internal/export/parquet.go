@@ -0,0 +1,68 @@
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+// parquetRowGroupSize - количество строк в группе; группа сбрасывается
+// в выходной поток целиком, поэтому размер ограничивает расход памяти
+const parquetRowGroupSize = 10000
+
+type parquetPerson struct {
+	ID          string    `parquet:"id"`
+	Name        string    `parquet:"name"`
+	Surname     string    `parquet:"surname"`
+	Patronymic  *string   `parquet:"patronymic,optional"`
+	Age         *int32    `parquet:"age,optional"`
+	Gender      *string   `parquet:"gender,optional"`
+	Nationality *string   `parquet:"nationality,optional"`
+	CreatedAt   time.Time `parquet:"created_at,timestamp(microsecond)"`
+	UpdatedAt   time.Time `parquet:"updated_at,timestamp(microsecond)"`
+}
+
+type parquetWriter struct {
+	w    *parquet.GenericWriter[parquetPerson]
+	rows []parquetPerson
+	n    int
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		w:    parquet.NewGenericWriter[parquetPerson](w, parquet.Compression(&parquet.Snappy)),
+		rows: make([]parquetPerson, 1),
+	}
+}
+
+func (p *parquetWriter) Write(person *model.Person) error {
+	row := parquetPerson{
+		ID:          person.ID.String(),
+		Name:        person.Name,
+		Surname:     person.Surname,
+		Patronymic:  person.Patronymic,
+		Gender:      person.Gender,
+		Nationality: person.Nationality,
+		CreatedAt:   person.CreatedAt,
+		UpdatedAt:   person.UpdatedAt,
+	}
+	if person.Age != nil {
+		age := int32(*person.Age)
+		row.Age = &age
+	}
+	p.rows[0] = row
+	if _, err := p.w.Write(p.rows); err != nil {
+		return err
+	}
+	p.n++
+	if p.n%parquetRowGroupSize == 0 {
+		return p.w.Flush()
+	}
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+type compiler struct {
+	prefix string
+	args   map[string]interface{}
+	n      int
+}
+
+// Compile преобразует выражение в SQL-условие с именованными параметрами
+// вида :<prefix>N, значения которых добавляются в args
+func Compile(expr Expr, prefix string, args map[string]interface{}) string {
+	c := &compiler{prefix: prefix, args: args}
+	return expr.compile(c)
+}
+
+func (c *compiler) bind(v interface{}) string {
+	name := fmt.Sprintf("%s%d", c.prefix, c.n)
+	c.n++
+	c.args[name] = v
+	return ":" + name
+}
+
+func (e *And) compile(c *compiler) string {
+	return "(" + e.Left.compile(c) + " AND " + e.Right.compile(c) + ")"
+}
+
+func (e *Or) compile(c *compiler) string {
+	return "(" + e.Left.compile(c) + " OR " + e.Right.compile(c) + ")"
+}
+
+func (e *Not) compile(c *compiler) string {
+	return "NOT (" + e.Expr.compile(c) + ")"
+}
+
+func (e *Compare) compile(c *compiler) string {
+	// Имя поля взято из белого списка fields, поэтому его можно подставлять в запрос
+	switch e.Op {
+	case "~":
+		return fmt.Sprintf("%s ILIKE %s", e.Field, c.bind("%"+escapeLike(e.Value.(string))+"%"))
+	case "!=":
+		return fmt.Sprintf("%s <> %s", e.Field, c.bind(e.Value))
+	default:
+		return fmt.Sprintf("%s %s %s", e.Field, e.Op, c.bind(e.Value))
+	}
+}
+
+func (e *In) compile(c *compiler) string {
+	params := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		params[i] = c.bind(v)
+	}
+	op := "IN"
+	if e.Negate {
+		op = "NOT IN"
+	}
+	return fmt.Sprintf("%s %s (%s)", e.Field, op, strings.Join(params, ", "))
+}
+
+func (e *IsNull) compile(c *compiler) string {
+	if e.Negate {
+		return e.Field + " IS NOT NULL"
+	}
+	return e.Field + " IS NULL"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
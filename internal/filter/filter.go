@@ -0,0 +1,144 @@
+// Package filter реализует язык выражений для фильтрации списка людей,
+// например: age>=30 and (gender=female or nationality in (RU,UA)).
+// Выражение разбирается в AST и компилируется в параметризованный SQL.
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error описывает ошибку в выражении фильтра с указанием проблемного токена
+type Error struct {
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("filter: %s at position %d", e.Msg, e.Pos)
+	}
+	return fmt.Sprintf("filter: %s at position %d near %q", e.Msg, e.Pos, e.Token)
+}
+
+type fieldType int
+
+const (
+	typeText fieldType = iota
+	typeInt
+	typeTime
+)
+
+// fields перечисляет поля, доступные в выражениях, и их типы
+var fields = map[string]fieldType{
+	"name":        typeText,
+	"surname":     typeText,
+	"patronymic":  typeText,
+	"age":         typeInt,
+	"gender":      typeText,
+	"nationality": typeText,
+	"created_at":  typeTime,
+	"updated_at":  typeTime,
+}
+
+// Expr - узел AST выражения фильтра
+type Expr interface {
+	compile(c *compiler) string
+}
+
+// And - конъюнкция выражений
+type And struct{ Left, Right Expr }
+
+// Or - дизъюнкция выражений
+type Or struct{ Left, Right Expr }
+
+// Not - отрицание выражения
+type Not struct{ Expr Expr }
+
+// Compare - сравнение поля со значением.
+// Op: =, !=, <, <=, >, >= или ~ (поиск подстроки без учета регистра)
+type Compare struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+// In - проверка вхождения значения поля в список
+type In struct {
+	Field  string
+	Values []interface{}
+	Negate bool
+}
+
+// IsNull - проверка поля на NULL
+type IsNull struct {
+	Field  string
+	Negate bool
+}
+
+// convertValue приводит литерал к типу поля
+func convertValue(field string, tok token) (interface{}, error) {
+	switch fields[field] {
+	case typeInt:
+		if tok.kind != tokNumber {
+			return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: fmt.Sprintf("field %s expects an integer", field)}
+		}
+		v, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: "integer out of range"}
+		}
+		return v, nil
+	case typeTime:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if v, err := time.Parse(layout, tok.text); err == nil {
+				return v, nil
+			}
+		}
+		return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: fmt.Sprintf("field %s expects a date (YYYY-MM-DD) or RFC 3339 timestamp", field)}
+	default:
+		return tok.text, nil
+	}
+}
+
+func sortedFields() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func unknownField(tok token) error {
+	return &Error{Pos: tok.pos, Token: tok.text, Msg: "unknown field, expected one of " + strings.Join(sortedFields(), ", ")}
+}
+
+// Fields возвращает поля, используемые в выражении
+func Fields(expr Expr) []string {
+	var names []string
+	var walk func(Expr)
+	walk = func(e Expr) {
+		switch e := e.(type) {
+		case *And:
+			walk(e.Left)
+			walk(e.Right)
+		case *Or:
+			walk(e.Left)
+			walk(e.Right)
+		case *Not:
+			walk(e.Expr)
+		case *Compare:
+			names = append(names, e.Field)
+		case *In:
+			names = append(names, e.Field)
+		case *IsNull:
+			names = append(names, e.Field)
+		}
+	}
+	walk(expr)
+	return names
+}
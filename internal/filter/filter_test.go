@@ -0,0 +1,167 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []token
+	}{
+		{"age>=30", []token{
+			{tokIdent, "age", 1}, {tokOp, ">=", 4}, {tokNumber, "30", 6}, {tokEOF, "", 8},
+		}},
+		{"name != 'O''Brien'", []token{
+			{tokIdent, "name", 1}, {tokOp, "!=", 6}, {tokString, "O'Brien", 9}, {tokEOF, "", 19},
+		}},
+		{`фамилия~"Иван" ,(-5)`, []token{
+			{tokIdent, "фамилия", 1}, {tokOp, "~", 8}, {tokString, "Иван", 9}, {tokComma, ",", 16},
+			{tokLParen, "(", 17}, {tokNumber, "-5", 18}, {tokRParen, ")", 20}, {tokEOF, "", 21},
+		}},
+		{"created_at<2024-01-02T03:04:05+03:00", []token{
+			{tokIdent, "created_at", 1}, {tokOp, "<", 11}, {tokIdent, "2024-01-02T03:04:05+03:00", 12}, {tokEOF, "", 37},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := lex(tt.input)
+		if err != nil {
+			t.Errorf("lex(%q): %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lex(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+	}{
+		{"name='Иван", 6},
+		{"age ! 3", 5},
+		{"age = 3;", 8},
+	}
+	for _, tt := range tests {
+		_, err := lex(tt.input)
+		var ferr *Error
+		if !errors.As(err, &ferr) {
+			t.Errorf("lex(%q) error = %v, want *Error", tt.input, err)
+			continue
+		}
+		if ferr.Pos != tt.pos {
+			t.Errorf("lex(%q) error position = %d, want %d", tt.input, ferr.Pos, tt.pos)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  Expr
+	}{
+		{"age=30", &Compare{Field: "age", Op: "=", Value: 30}},
+		{"NAME ~ иван", &Compare{Field: "name", Op: "~", Value: "иван"}},
+		{"created_at >= 2024-01-02", &Compare{Field: "created_at", Op: ">=", Value: day}},
+		// and связывает сильнее or
+		{"age=1 or age=2 and age=3", &Or{
+			Left:  &Compare{Field: "age", Op: "=", Value: 1},
+			Right: &And{Left: &Compare{Field: "age", Op: "=", Value: 2}, Right: &Compare{Field: "age", Op: "=", Value: 3}},
+		}},
+		{"(age=1 or age=2) and age=3", &And{
+			Left:  &Or{Left: &Compare{Field: "age", Op: "=", Value: 1}, Right: &Compare{Field: "age", Op: "=", Value: 2}},
+			Right: &Compare{Field: "age", Op: "=", Value: 3},
+		}},
+		{"not age=1 and gender=male", &And{
+			Left:  &Not{Expr: &Compare{Field: "age", Op: "=", Value: 1}},
+			Right: &Compare{Field: "gender", Op: "=", Value: "male"},
+		}},
+		{"nationality not in (RU, 'UA')", &In{Field: "nationality", Values: []interface{}{"RU", "UA"}, Negate: true}},
+		{"gender is not null", &IsNull{Field: "gender", Negate: true}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+		token string
+	}{
+		{"", 1, ""},
+		{"email=x", 1, "email"},
+		{"age=abc", 5, "abc"},
+		{"age~3", 4, "~"},
+		{"age=99999999999999999999", 5, "99999999999999999999"},
+		{"created_at>yesterday", 12, "yesterday"},
+		{"(age=1", 7, ""},
+		{"age=1 age=2", 7, "age"},
+		{"gender is male", 11, "male"},
+		{"age not 3", 9, "3"},
+		{"nationality in (RU UA)", 20, "UA"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		var ferr *Error
+		if !errors.As(err, &ferr) {
+			t.Errorf("Parse(%q) error = %v, want *Error", tt.input, err)
+			continue
+		}
+		if ferr.Pos != tt.pos || ferr.Token != tt.token {
+			t.Errorf("Parse(%q) error at %d near %q, want %d near %q", tt.input, ferr.Pos, ferr.Token, tt.pos, tt.token)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		input string
+		sql   string
+		args  map[string]interface{}
+	}{
+		{
+			"age>=30 and (gender=female or nationality in (RU,UA))",
+			"(age >= :f0 AND (gender = :f1 OR nationality IN (:f2, :f3)))",
+			map[string]interface{}{"f0": 30, "f1": "female", "f2": "RU", "f3": "UA"},
+		},
+		{
+			"not name != Иван or surname is null",
+			"(NOT (name <> :f0) OR surname IS NULL)",
+			map[string]interface{}{"f0": "Иван"},
+		},
+		{
+			`surname ~ "50%_\"`,
+			"surname ILIKE :f0",
+			map[string]interface{}{"f0": `%50\%\_\\%`},
+		},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		args := map[string]interface{}{}
+		if sql := Compile(expr, "f", args); sql != tt.sql {
+			t.Errorf("Compile(%q) = %q, want %q", tt.input, sql, tt.sql)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("Compile(%q) args = %v, want %v", tt.input, args, tt.args)
+		}
+	}
+}
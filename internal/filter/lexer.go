@@ -0,0 +1,116 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos - позиция начала токена в символах (с единицы)
+	pos int
+}
+
+// lex разбивает выражение на токены
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: pos})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: pos})
+			i++
+		case r == '=' || r == '~':
+			tokens = append(tokens, token{kind: tokOp, text: string(r), pos: pos})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{kind: tokOp, text: string(runes[i : i+2]), pos: pos})
+				i += 2
+				continue
+			}
+			if r == '!' {
+				return nil, &Error{Pos: pos, Token: "!", Msg: "unexpected character"}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: string(r), pos: pos})
+			i++
+		case r == '\'' || r == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes); j++ {
+				if runes[j] == r {
+					// Удвоенная кавычка внутри строки экранирует саму себя
+					if j+1 < len(runes) && runes[j+1] == r {
+						sb.WriteRune(r)
+						j++
+						continue
+					}
+					break
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, &Error{Pos: pos, Token: string(runes[i:]), Msg: "unterminated string"}
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: pos})
+			i = j + 1
+		case isWordRune(r):
+			j := i
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+			text := string(runes[i:j])
+			kind := tokIdent
+			if isNumber(text) {
+				kind = tokNumber
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: pos})
+			i = j
+		default:
+			return nil, &Error{Pos: pos, Token: string(r), Msg: "unexpected character"}
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, pos: utf8.RuneCountInString(input) + 1})
+	return tokens, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' || r == ':' || r == '+'
+}
+
+func isNumber(s string) bool {
+	for i, r := range s {
+		if r == '-' && i == 0 && len(s) > 1 {
+			continue
+		}
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,209 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	maxDepth    = 32
+	maxInValues = 100
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// Parse разбирает выражение фильтра. Грамматика:
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field op value
+//	           | field ["not"] "in" "(" value { "," value } ")"
+//	           | field "is" ["not"] "null"
+func Parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, &Error{Pos: 1, Msg: "empty expression"}
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: "unexpected token, expected and/or"}
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokIdent && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		tok := p.peek()
+		return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: "expression is nested too deeply"}
+	}
+
+	if p.keyword("not") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	}
+	if p.peek().kind == tokLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, expected(tok, "closing parenthesis")
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	fieldTok := p.next()
+	if fieldTok.kind != tokIdent {
+		return nil, expected(fieldTok, "field name")
+	}
+	field := strings.ToLower(fieldTok.text)
+	if _, ok := fields[field]; !ok {
+		return nil, unknownField(fieldTok)
+	}
+
+	if p.keyword("is") {
+		negate := p.keyword("not")
+		if !p.keyword("null") {
+			return nil, expected(p.peek(), "null")
+		}
+		return &IsNull{Field: field, Negate: negate}, nil
+	}
+	negate := p.keyword("not")
+	if p.keyword("in") {
+		values, err := p.parseList(field)
+		if err != nil {
+			return nil, err
+		}
+		return &In{Field: field, Values: values, Negate: negate}, nil
+	}
+	if negate {
+		return nil, expected(p.peek(), "in")
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokOp {
+		return nil, expected(opTok, "operator (=, !=, <, <=, >, >=, ~, in, is)")
+	}
+	if opTok.text == "~" && fields[field] != typeText {
+		return nil, &Error{Pos: opTok.pos, Token: opTok.text, Msg: fmt.Sprintf("operator ~ is not supported for field %s", field)}
+	}
+	value, err := p.parseValue(field)
+	if err != nil {
+		return nil, err
+	}
+	return &Compare{Field: field, Op: opTok.text, Value: value}, nil
+}
+
+func (p *parser) parseList(field string) ([]interface{}, error) {
+	if tok := p.next(); tok.kind != tokLParen {
+		return nil, expected(tok, "opening parenthesis")
+	}
+	var values []interface{}
+	for {
+		value, err := p.parseValue(field)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if len(values) > maxInValues {
+			tok := p.peek()
+			return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: fmt.Sprintf("too many values in list, maximum is %d", maxInValues)}
+		}
+		tok := p.next()
+		if tok.kind == tokRParen {
+			return values, nil
+		}
+		if tok.kind != tokComma {
+			return nil, expected(tok, "comma or closing parenthesis")
+		}
+	}
+}
+
+func (p *parser) parseValue(field string) (interface{}, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokIdent, tokNumber, tokString:
+		return convertValue(field, tok)
+	default:
+		return nil, expected(tok, "value")
+	}
+}
+
+func expected(tok token, what string) error {
+	if tok.kind == tokEOF {
+		return &Error{Pos: tok.pos, Msg: "unexpected end of expression, expected " + what}
+	}
+	return &Error{Pos: tok.pos, Token: tok.text, Msg: "unexpected token, expected " + what}
+}
@@ -0,0 +1,219 @@
+// Package importer загружает людей из файлов CSV и NDJSON: строки читаются
+// потоково, проверяются, обогащаются пакетами и записываются через COPY,
+// а прогресс и ошибочные строки сохраняются в задаче импорта.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/shenikar/Name-analyzer/internal/db"
+	"github.com/shenikar/Name-analyzer/internal/enrich"
+	"github.com/shenikar/Name-analyzer/internal/model"
+	"github.com/shenikar/Name-analyzer/internal/validate"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// batchSize - количество строк, обогащаемых и записываемых за раз
+const batchSize = 500
+
+// Fields перечисляет поля записи, которые можно сопоставить колонкам файла
+var Fields = []string{"name", "surname", "patronymic"}
+
+// Options задает параметры разбора файла
+type Options struct {
+	Format  string
+	Mapping map[string]string
+	// Delimiter - разделитель колонок CSV
+	Delimiter rune
+	// Enrich включает обогащение данных внешними API
+	Enrich bool
+}
+
+type Importer struct {
+	DB       *db.DB
+	Enricher *enrich.Enricher
+	Logger   *slog.Logger
+
+	// ctx - контекст жизни сервиса, отмена которого прерывает фоновые импорты
+	ctx  context.Context
+	jobs sync.WaitGroup
+}
+
+// New создает импортер, фоновые задачи которого прерываются отменой ctx
+func New(ctx context.Context, database *db.DB, enricher *enrich.Enricher, logger *slog.Logger) *Importer {
+	return &Importer{DB: database, Enricher: enricher, Logger: logger, ctx: ctx}
+}
+
+// Go выполняет fn в фоне с контекстом жизни сервиса; Wait дожидается завершения таких задач
+func (im *Importer) Go(fn func(ctx context.Context)) {
+	im.jobs.Add(1)
+	go func() {
+		defer im.jobs.Done()
+		fn(im.ctx)
+	}()
+}
+
+// Wait ждет завершения задач, запущенных через Go
+func (im *Importer) Wait() {
+	im.jobs.Wait()
+}
+
+// Run выполняет импорт задачи imp из r, обновляя ее прогресс после каждой пачки.
+// Паника и отмена ctx завершают задачу ошибкой; итоговый статус сохраняется и после отмены ctx.
+func (im *Importer) Run(ctx context.Context, imp *model.Import, r io.Reader, opts Options) {
+	defer func() {
+		if p := recover(); p != nil {
+			im.Logger.ErrorContext(ctx, "import panicked", "import_id", imp.ID, "error", p, "stack", string(debug.Stack()))
+			im.finish(ctx, imp, errors.New("internal error"))
+		}
+	}()
+	imp.Status = db.ImportRunning
+	im.save(ctx, imp)
+
+	err := im.run(ctx, imp, r, opts)
+	if err != nil && ctx.Err() != nil {
+		err = errors.New("import was interrupted by a service shutdown")
+	}
+	if err != nil {
+		im.Logger.ErrorContext(ctx, "import failed", "import_id", imp.ID, "error", err)
+	}
+	im.finish(ctx, imp, err)
+}
+
+// finish сохраняет итоговый статус задачи: ошибкой, если err не nil
+func (im *Importer) finish(ctx context.Context, imp *model.Import, err error) {
+	if err != nil {
+		msg := err.Error()
+		imp.Error = &msg
+		imp.Status = db.ImportFailed
+	} else {
+		imp.Status = db.ImportCompleted
+	}
+	im.save(context.WithoutCancel(ctx), imp)
+}
+
+func (im *Importer) run(ctx context.Context, imp *model.Import, r io.Reader, opts Options) error {
+	var rows rowReader
+	switch opts.Format {
+	case FormatCSV:
+		cr, err := newCSVReader(r, opts.Mapping, opts.Delimiter)
+		if err != nil {
+			return err
+		}
+		rows = cr
+	case FormatNDJSON:
+		rows = newNDJSONReader(r, opts.Mapping)
+	default:
+		return fmt.Errorf("unsupported format %q", opts.Format)
+	}
+
+	var (
+		persons   []*model.Person
+		validRows []*row
+		failed    []model.ImportError
+	)
+	flush := func() error {
+		if err := im.writeBatch(ctx, imp, persons, validRows, failed, opts.Enrich); err != nil {
+			return err
+		}
+		persons, validRows, failed = persons[:0], validRows[:0], failed[:0]
+		im.save(ctx, imp)
+		return nil
+	}
+	for {
+		rw, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		imp.TotalRows++
+		person, err := toPerson(rw)
+		if err != nil {
+			failed = append(failed, model.ImportError{RowNumber: rw.number, Raw: rw.raw, Error: err.Error()})
+		} else {
+			persons = append(persons, person)
+			validRows = append(validRows, rw)
+		}
+		if len(persons)+len(failed) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// writeBatch обогащает и записывает пачку корректных строк и сохраняет ошибочные
+func (im *Importer) writeBatch(ctx context.Context, imp *model.Import, persons []*model.Person, rows []*row, failed []model.ImportError, enrichData bool) error {
+	if len(persons) > 0 {
+		if enrichData {
+			names := make([]string, len(persons))
+			for i, p := range persons {
+				names[i] = p.Name
+			}
+			if err := im.DB.ConsumeEnrichmentQuota(ctx, len(enrich.Unique(names))); err != nil {
+				return err
+			}
+			enriched, outcomes := im.Enricher.EnrichBatch(ctx, names)
+			if err := im.DB.RecordEnrichmentOutcomes(ctx, outcomes); err != nil {
+				im.Logger.ErrorContext(ctx, "failed to record enrichment outcomes", "import_id", imp.ID, "error", err)
+			}
+			for _, p := range persons {
+				data := enriched[p.Name]
+				p.Age, p.Gender, p.Nationality = data.Age, data.Gender, data.Nationality
+			}
+		}
+		if err := im.DB.CopyPersons(ctx, persons); err != nil {
+			// COPY выполняется целиком, поэтому вся пачка считается ошибочной
+			im.Logger.ErrorContext(ctx, "failed to copy import batch", "import_id", imp.ID, "error", err)
+			for _, rw := range rows {
+				failed = append(failed, model.ImportError{RowNumber: rw.number, Raw: rw.raw, Error: "db error"})
+			}
+		} else {
+			imp.ImportedRows += len(persons)
+		}
+	}
+	if err := im.DB.AddImportErrors(ctx, imp.ID, failed); err != nil {
+		return fmt.Errorf("failed to save error rows: %w", err)
+	}
+	imp.FailedRows += len(failed)
+	return nil
+}
+
+func (im *Importer) save(ctx context.Context, imp *model.Import) {
+	if err := im.DB.UpdateImport(ctx, imp); err != nil {
+		im.Logger.ErrorContext(ctx, "failed to save import progress", "import_id", imp.ID, "error", err)
+	}
+}
+
+// toPerson проверяет строку и преобразует ее в запись
+func toPerson(rw *row) (*model.Person, error) {
+	if rw.err != nil {
+		return nil, rw.err
+	}
+	req := model.PersonRequest{Name: rw.fields["name"], Surname: rw.fields["surname"]}
+	if patronymic := rw.fields["patronymic"]; patronymic != "" {
+		req.Patronymic = &patronymic
+	}
+	if violations := validate.PersonRequest(&req, false); len(violations) > 0 {
+		msgs := make([]string, len(violations))
+		for i, v := range violations {
+			msgs[i] = v.Field + " " + v.Message
+		}
+		return nil, errors.New(strings.Join(msgs, "; "))
+	}
+	return &model.Person{Name: req.Name, Surname: req.Surname, Patronymic: req.Patronymic}, nil
+}
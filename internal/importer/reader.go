@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// row - одна строка файла импорта
+type row struct {
+	number int
+	// raw - исходное содержимое строки для отчета об ошибках
+	raw    string
+	fields map[string]string
+	err    error
+}
+
+// rowReader последовательно читает строки файла импорта
+type rowReader interface {
+	// Next возвращает очередную строку или io.EOF
+	Next() (*row, error)
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	mapping map[string]int
+}
+
+func newCSVReader(r io.Reader, mapping map[string]string, delimiter rune) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = delimiter
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	indexes := make(map[string]int, len(mapping))
+	for field, column := range mapping {
+		i, ok := columns[column]
+		if !ok {
+			if field == "patronymic" {
+				continue
+			}
+			return nil, fmt.Errorf("column %q for field %s not found in csv header", column, field)
+		}
+		indexes[field] = i
+	}
+	return &csvReader{r: cr, mapping: indexes}, nil
+}
+
+func (c *csvReader) Next() (*row, error) {
+	record, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return &row{number: parseErr.StartLine, err: err}, nil
+		}
+		return nil, err
+	}
+	// Номер строки файла, с которой начинается запись (поля в кавычках могут занимать несколько строк)
+	line, _ := c.r.FieldPos(0)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = c.r.Comma
+	w.Write(record)
+	w.Flush()
+
+	fields := make(map[string]string, len(c.mapping))
+	for field, i := range c.mapping {
+		if i < len(record) {
+			fields[field] = record[i]
+		}
+	}
+	return &row{number: line, raw: strings.TrimRight(buf.String(), "\r\n"), fields: fields}, nil
+}
+
+// maxLineSize ограничивает длину одной строки NDJSON
+const maxLineSize = 1 << 20
+
+type ndjsonReader struct {
+	s       *bufio.Scanner
+	mapping map[string]string
+	line    int
+}
+
+func newNDJSONReader(r io.Reader, mapping map[string]string) *ndjsonReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &ndjsonReader{s: s, mapping: mapping}
+}
+
+func (n *ndjsonReader) Next() (*row, error) {
+	for n.s.Scan() {
+		n.line++
+		line := bytes.TrimSpace(n.s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		r := &row{number: n.line, raw: string(line)}
+		var obj map[string]interface{}
+		if err := json.Unmarshal(line, &obj); err != nil {
+			r.err = fmt.Errorf("invalid json: %w", err)
+			return r, nil
+		}
+		r.fields = make(map[string]string, len(n.mapping))
+		for field, key := range n.mapping {
+			switch v := obj[key].(type) {
+			case nil:
+			case string:
+				r.fields[field] = v
+			default:
+				r.fields[field] = fmt.Sprint(v)
+			}
+		}
+		return r, nil
+	}
+	if err := n.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,79 @@
+// Package metrics описывает метрики Prometheus сервиса. Метрики регистрируются
+// в реестре по умолчанию и отдаются обработчиком Handler.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "name_analyzer"
+
+var (
+	// HTTPRequests - обработанные запросы по шаблону маршрута mux и статусу ответа
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route and status.",
+	}, []string{"route", "status"})
+
+	// HTTPDuration - длительность обработки запросов
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "status"})
+
+	// ProviderDuration - длительность обращений к провайдерам обогащения, включая неудачные
+	ProviderDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "enrich_provider_request_duration_seconds",
+		Help:      "Enrichment provider call latency.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2, 3, 5},
+	}, []string{"provider"})
+
+	// ProviderErrors - неудачные обращения к провайдерам обогащения
+	ProviderErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "enrich_provider_errors_total",
+		Help:      "Failed enrichment provider calls.",
+	}, []string{"provider"})
+
+	// RateLimitFailOpen - запросы, пропущенные без проверки лимита из-за ошибки хранилища лимитов
+	RateLimitFailOpen = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ratelimit_fail_open_total",
+		Help:      "Requests allowed because the rate limit store failed.",
+	})
+
+	// Persons - количество записей по арендаторам
+	Persons = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "persons",
+		Help:      "Stored persons by tenant.",
+	}, []string{"tenant"})
+)
+
+// RegisterDBStats добавляет метрики пула соединений из sql.DB.Stats
+func RegisterDBStats(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// SetPersons заменяет значения Persons; арендаторы без записей пропадают из метрики
+func SetPersons(counts map[string]int) {
+	Persons.Reset()
+	for tenant, n := range counts {
+		Persons.WithLabelValues(tenant).Set(float64(n))
+	}
+}
+
+// Handler отдает метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey - ключ доступа к API. Хранится только хеш ключа, сам ключ показывается один раз при создании.
+type APIKey struct {
+	ID   uuid.UUID `db:"id" json:"id"`
+	Name string    `db:"name" json:"name"`
+	// Prefix - начало ключа, по которому его можно узнать в списке
+	Prefix string   `db:"prefix" json:"prefix"`
+	Scopes []string `db:"-" json:"scopes"`
+	// Tenant - арендатор, к данным которого дает доступ ключ; nil - ключ не привязан к арендатору
+	Tenant     *string    `db:"tenant_id" json:"tenant,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
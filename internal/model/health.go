@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Результаты проверок готовности
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+	HealthFail        = "fail"
+)
+
+// HealthReport - отчет о состоянии сервиса: unavailable, если не прошла критичная проверка,
+// degraded - если только некритичные
+type HealthReport struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck - результат проверки одной зависимости
+type HealthCheck struct {
+	Status   string `json:"status" example:"ok"`
+	Critical bool   `json:"critical" example:"true"`
+	Error    string `json:"error,omitempty"`
+	// Version и ExpectedVersion - версия схемы в БД и версия, которую ожидает сервис
+	Version         *uint `json:"version,omitempty" example:"10"`
+	ExpectedVersion *uint `json:"expected_version,omitempty" example:"10"`
+	// Circuit - состояние автомата отключения провайдера: closed, open или half_open
+	Circuit             string     `json:"circuit,omitempty" example:"closed"`
+	ConsecutiveFailures int        `json:"consecutive_failures,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+}
@@ -17,6 +17,8 @@ type Person struct {
 	Nationality *string   `db:"nationality" json:"nationality,omitempty" example:"RU"`
 	CreatedAt   time.Time `db:"created_at" json:"created_at" example:"2024-03-20T15:04:05Z"`
 	UpdatedAt   time.Time `db:"updated_at" json:"updated_at" example:"2024-03-20T15:04:05Z"`
+	// Score - релевантность записи, заполняется только при поиске
+	Score *float64 `db:"score" json:"score,omitempty" example:"0.87"`
 }
 
 // PersonRequest представляет запрос на создание/обновление записи
@@ -29,7 +31,174 @@ type PersonRequest struct {
 	Nationality *string `json:"nationality,omitempty" example:"RU"`
 }
 
-// ErrorResponse представляет ответ с ошибкой
-type ErrorResponse struct {
-	Error string `json:"error" example:"некорректный запрос"`
+// Problem - описание ошибки в формате RFC 7807 (application/problem+json)
+type Problem struct {
+	Type      string           `json:"type" example:"about:blank"`
+	Title     string           `json:"title" example:"Bad Request"`
+	Status    int              `json:"status" example:"400"`
+	Detail    string           `json:"detail,omitempty" example:"request contains invalid fields"`
+	Instance  string           `json:"instance,omitempty" example:"/api/v1/persons"`
+	RequestID string           `json:"request_id,omitempty" example:"4f6c1b7e-2a0d-4c55-9a8e-1f2b3c4d5e6f"`
+	Errors    []FieldViolation `json:"errors,omitempty"`
+}
+
+// FieldViolation описывает нарушение проверки одного поля
+type FieldViolation struct {
+	Field   string `json:"field" example:"nationality"`
+	Code    string `json:"code" example:"not_allowed"`
+	Message string `json:"message" example:"must be an ISO 3166-1 alpha-2 country code"`
+}
+
+// PersonList представляет страницу списка людей с метаданными пагинации
+type PersonList struct {
+	Items          []*Person `json:"items"`
+	Total          int64     `json:"total" example:"42"`
+	TotalEstimated bool      `json:"total_estimated,omitempty" example:"false"`
+	Limit          int       `json:"limit" example:"10"`
+	Offset         *int      `json:"offset,omitempty" example:"0"`
+	Cursor         string    `json:"cursor,omitempty"`
+	NextCursor     string    `json:"next_cursor,omitempty"`
+	PrevCursor     string    `json:"prev_cursor,omitempty"`
+	Links          PageLinks `json:"links"`
+}
+
+// PageLinks содержит ссылки на текущую и соседние страницы списка
+type PageLinks struct {
+	Self string `json:"self" example:"/api/v1/persons?limit=10&offset=10"`
+	Next string `json:"next,omitempty" example:"/api/v1/persons?limit=10&offset=20"`
+	Prev string `json:"prev,omitempty" example:"/api/v1/persons?limit=10&offset=0"`
+}
+
+// DuplicateCandidate представляет запись, вероятно дублирующую другую
+type DuplicateCandidate struct {
+	Person *Person `json:"person"`
+	// Score - уверенность в том, что записи описывают одного человека, от 0 до 1
+	Score   float64  `json:"score" example:"0.92"`
+	Reasons []string `json:"reasons" example:"surname_phonetic,name_similar,age_close"`
+}
+
+// MergeRequest представляет запрос на слияние записей-дублей в одну
+type MergeRequest struct {
+	// TargetID - запись, которая сохраняется после слияния
+	TargetID uuid.UUID `json:"target_id" example:"39755c70-2ddb-4a62-90ea-1eeaf07a545a"`
+	// SourceIDs - записи, которые вливаются в целевую и удаляются
+	SourceIDs []uuid.UUID `json:"source_ids"`
+	// Fields задает, из какой записи брать значение поля. По умолчанию берется
+	// значение целевой записи, а если оно пустое - первое непустое из источников
+	Fields map[string]uuid.UUID `json:"fields,omitempty"`
+}
+
+// BatchItemResult представляет результат обработки одного элемента пакетного запроса
+type BatchItemResult struct {
+	Index  int        `json:"index" example:"0"`
+	Status int        `json:"status" example:"201"`
+	ID     *uuid.UUID `json:"id,omitempty" example:"39755c70-2ddb-4a62-90ea-1eeaf07a545a"`
+	Person *Person    `json:"person,omitempty"`
+	Error  string     `json:"error,omitempty" example:"request contains invalid fields"`
+	// Errors - нарушения проверки полей элемента
+	Errors []FieldViolation `json:"errors,omitempty"`
+}
+
+// BatchResponse представляет итог пакетного создания записей
+type BatchResponse struct {
+	Created int               `json:"created" example:"9"`
+	Failed  int               `json:"failed" example:"1"`
+	Items   []BatchItemResult `json:"items"`
+}
+
+// Import представляет задачу импорта людей из файла
+type Import struct {
+	ID     uuid.UUID `db:"id" json:"id" example:"8a0c1d3e-5f6a-4b7c-8d9e-0f1a2b3c4d5e"`
+	Format string    `db:"format" json:"format" example:"csv"`
+	// Status: pending, running, completed или failed
+	Status string `db:"status" json:"status" example:"running"`
+	// Mapping сопоставляет поля записи (name, surname, patronymic) колонкам файла
+	Mapping      map[string]string `db:"-" json:"mapping"`
+	TotalRows    int               `db:"total_rows" json:"total_rows" example:"1500"`
+	ImportedRows int               `db:"imported_rows" json:"imported_rows" example:"1480"`
+	FailedRows   int               `db:"failed_rows" json:"failed_rows" example:"20"`
+	Error        *string           `db:"error" json:"error,omitempty"`
+	CreatedAt    time.Time         `db:"created_at" json:"created_at" example:"2024-03-20T15:04:05Z"`
+	UpdatedAt    time.Time         `db:"updated_at" json:"updated_at" example:"2024-03-20T15:04:05Z"`
+	FinishedAt   *time.Time        `db:"finished_at" json:"finished_at,omitempty" example:"2024-03-20T15:05:10Z"`
+}
+
+// ImportError представляет строку файла импорта, которую не удалось загрузить
+type ImportError struct {
+	RowNumber int    `db:"row_number" json:"row_number" example:"42"`
+	Raw       string `db:"raw" json:"raw" example:"Иван,,Иванович"`
+	Error     string `db:"error" json:"error" example:"surname is required"`
+}
+
+// Stats представляет агрегированную статистику по людям
+type Stats struct {
+	Total               int64          `json:"total" example:"120"`
+	ByGender            []StatsBucket  `json:"by_gender"`
+	ByNationality       []StatsBucket  `json:"by_nationality"`
+	ByAge               []AgeBucket    `json:"by_age"`
+	Completeness        Completeness   `json:"completeness"`
+	GenderByNationality []CrossTabCell `json:"gender_by_nationality"`
+}
+
+// StatsBucket - количество записей с заданным значением поля; пустой Key означает отсутствие значения
+type StatsBucket struct {
+	Key   *string `json:"key" example:"male"`
+	Count int64   `json:"count" example:"64"`
+}
+
+// AgeBucket - количество записей с возрастом в диапазоне [From, To]; пустые границы означают неизвестный возраст
+type AgeBucket struct {
+	From  *int  `json:"from" example:"30"`
+	To    *int  `json:"to" example:"39"`
+	Count int64 `json:"count" example:"27"`
+}
+
+// Completeness показывает, сколько записей удалось обогатить
+type Completeness struct {
+	WithAge         int64 `json:"with_age" example:"110"`
+	WithGender      int64 `json:"with_gender" example:"115"`
+	WithNationality int64 `json:"with_nationality" example:"108"`
+	// Complete - записи, у которых заполнены возраст, пол и национальность
+	Complete int64 `json:"complete" example:"104"`
+	// Empty - записи без данных обогащения
+	Empty int64 `json:"empty" example:"3"`
+}
+
+// CrossTabCell - количество записей с заданным сочетанием национальности и пола
+type CrossTabCell struct {
+	Nationality *string `json:"nationality" example:"RU"`
+	Gender      *string `json:"gender" example:"female"`
+	Count       int64   `json:"count" example:"12"`
+}
+
+// EnrichmentOutcome описывает результат одного обращения к провайдеру обогащения
+type EnrichmentOutcome struct {
+	Provider   string    `db:"provider" json:"provider" example:"agify"`
+	Success    bool      `db:"success" json:"success" example:"true"`
+	Error      *string   `db:"error" json:"error,omitempty"`
+	DurationMS int       `db:"duration_ms" json:"duration_ms" example:"120"`
+	OccurredAt time.Time `db:"occurred_at" json:"occurred_at" example:"2024-03-20T15:04:05Z"`
+}
+
+// TimeSeries представляет динамику изменений записей и результатов обогащения
+type TimeSeries struct {
+	Interval string            `json:"interval" example:"day"`
+	From     time.Time         `json:"from" example:"2024-03-01T00:00:00Z"`
+	To       time.Time         `json:"to" example:"2024-03-31T00:00:00Z"`
+	Points   []TimeSeriesPoint `json:"points"`
+}
+
+// TimeSeriesPoint - показатели за интервал, начинающийся в Bucket
+type TimeSeriesPoint struct {
+	Bucket     time.Time                   `json:"bucket" example:"2024-03-20T00:00:00Z"`
+	Created    int64                       `json:"created" example:"15"`
+	Updated    int64                       `json:"updated" example:"4"`
+	Deleted    int64                       `json:"deleted" example:"1"`
+	Enrichment map[string]EnrichmentCounts `json:"enrichment"`
+}
+
+// EnrichmentCounts - количество успешных и неудачных обращений к провайдеру
+type EnrichmentCounts struct {
+	Success int64 `json:"success" example:"42"`
+	Failure int64 `json:"failure" example:"3"`
 }
@@ -0,0 +1,111 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	// Примеры из приложения A RFC 7396
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// Числа сохраняются без потери точности
+		{`{"n":12345678901234567890}`, `{"a":1.50}`, `{"a":1.50,"n":12345678901234567890}`},
+	}
+	for _, tt := range tests {
+		got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("Merge(%s, %s): %v", tt.doc, tt.patch, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Merge(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestMergeInvalid(t *testing.T) {
+	for _, p := range []string{``, `{"a":`, `{} {}`, `{"a":1}}`, `{"a":1}]`} {
+		if _, err := Merge([]byte(`{}`), []byte(p)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("Merge with patch %q error = %v, want ErrInvalidPatch", p, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"append to array", `{"foo":[1]}`, `[{"op":"add","path":"/foo/-","value":2}]`, `{"foo":[1,2]}`},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"replace with null", `{"a":1}`, `[{"op":"replace","path":"/a","value":null}]`, `{"a":null}`},
+		{"move", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"copy is independent", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			`{"a":{"b":1},"c":{"b":2}}`},
+		{"test passes", `{"baz":"qux","n":1.0}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/n","value":1}]`,
+			`{"baz":"qux","n":1.0}`},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"replace","path":"/m~0n","value":3}]`, `{"m~n":3}`},
+		{"replace root", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		{"empty patch", `{"a":1}`, `[]`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, patch string
+		want             error
+	}{
+		{"not an array", `{}`, `{"op":"add"}`, ErrInvalidPatch},
+		{"unknown op", `{}`, `[{"op":"frobnicate","path":"/a"}]`, ErrInvalidPatch},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, ErrInvalidPatch},
+		{"relative path", `{}`, `[{"op":"add","path":"a","value":1}]`, ErrInvalidPatch},
+		{"remove missing member", `{"a":1}`, `[{"op":"remove","path":"/b"}]`, ErrNotApplicable},
+		{"replace missing member", `{"a":1}`, `[{"op":"replace","path":"/b","value":1}]`, ErrNotApplicable},
+		{"add to missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`, ErrNotApplicable},
+		{"index out of range", `{"a":[1]}`, `[{"op":"add","path":"/a/2","value":1}]`, ErrNotApplicable},
+		{"leading zero index", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/01"}]`, ErrNotApplicable},
+		{"descend into scalar", `{"a":1}`, `[{"op":"test","path":"/a/b","value":1}]`, ErrNotApplicable},
+		{"move into itself", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, ErrNotApplicable},
+		{"test fails", `{"a":"1"}`, `[{"op":"test","path":"/a","value":1}]`, ErrTestFailed},
+		{"failure after success", `{"a":1}`, `[{"op":"remove","path":"/a"},{"op":"test","path":"/a","value":1}]`, ErrNotApplicable},
+	}
+	for _, tt := range tests {
+		got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+		if got != nil {
+			t.Errorf("%s: got document %s on error", tt.name, got)
+		}
+	}
+}
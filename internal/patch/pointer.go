@@ -0,0 +1,191 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// parsePointer разбирает JSON Pointer (RFC 6901) в список токенов
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// index разбирает индекс массива; "-" допустим только при добавлении в конец
+func index(token string, length int, appending bool) (int, error) {
+	if token == "-" && appending {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrNotApplicable, token)
+	}
+	i, err := strconv.Atoi(token)
+	limit := length
+	if appending {
+		limit++
+	}
+	if err != nil || i < 0 || i >= limit {
+		return 0, fmt.Errorf("%w: array index %q out of range", ErrNotApplicable, token)
+	}
+	return i, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch v := doc.(type) {
+		case map[string]any:
+			next, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q not found", ErrNotApplicable, token)
+			}
+			doc = next
+		case []any:
+			i, err := index(token, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = v[i]
+		default:
+			return nil, fmt.Errorf("%w: cannot descend into a scalar at %q", ErrNotApplicable, token)
+		}
+	}
+	return doc, nil
+}
+
+// update заменяет значение родителя последнего токена результатом fn
+func update(doc any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := fn(parent, path[len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	if len(path) == 1 {
+		return updated, nil
+	}
+	return update(doc, path[:len(path)-1], func(grand any, token string) (any, error) {
+		switch g := grand.(type) {
+		case map[string]any:
+			g[token] = updated
+		case []any:
+			i, _ := index(token, len(g), false)
+			g[i] = updated
+		}
+		return grand, nil
+	})
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			p[token] = value
+			return p, nil
+		case []any:
+			i, err := index(token, len(p), true)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		default:
+			return nil, fmt.Errorf("%w: cannot add to a scalar", ErrNotApplicable)
+		}
+	})
+}
+
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			if _, ok := p[token]; !ok {
+				return nil, fmt.Errorf("%w: member %q not found", ErrNotApplicable, token)
+			}
+			delete(p, token)
+			return p, nil
+		case []any:
+			i, err := index(token, len(p), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], p[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: cannot remove from a scalar", ErrNotApplicable)
+		}
+	})
+}
+
+// equal сравнивает значения по правилам RFC 6902: числа сравниваются по значению
+func equal(a, b any) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, err1 := an.Float64()
+		bf, err2 := bn.Float64()
+		return err1 == nil && err2 == nil && af == bf
+	}
+	if aok != bok {
+		return false
+	}
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
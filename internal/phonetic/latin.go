@@ -0,0 +1,161 @@
+package phonetic
+
+import "strings"
+
+func isVowel(r byte) bool {
+	return strings.IndexByte("AEIOUY", r) >= 0
+}
+
+// latin реализует упрощенный Metaphone с поправками на транслитерацию
+// славянских имен: W читается как V, сочетания ZH, KH, TS/TZ, SZ, CZ и SCH
+// сводятся к одному звуку, а сдвоенные согласные схлопываются
+func latin(word string) string {
+	w := strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return -1
+	}, strings.ToUpper(word))
+	if w == "" {
+		return ""
+	}
+
+	for _, p := range []string{"KN", "GN", "PN", "WR", "AE"} {
+		if strings.HasPrefix(w, p) {
+			w = w[1:]
+			break
+		}
+	}
+
+	at := func(i int) byte {
+		if i < 0 || i >= len(w) {
+			return 0
+		}
+		return w[i]
+	}
+	has := func(i int, s string) bool {
+		return strings.HasPrefix(w[i:], s)
+	}
+
+	var out []rune
+	for i := 0; i < len(w); i++ {
+		c := w[i]
+		if i > 0 && c == w[i-1] && c != 'C' {
+			continue
+		}
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			// Гласные значимы только в начале слова и сводятся к одному коду
+			if i == 0 {
+				out = append(out, 'A')
+			}
+		case 'B':
+			if !(i == len(w)-1 && at(i-1) == 'M') {
+				out = append(out, 'B')
+			}
+		case 'C':
+			switch {
+			case has(i, "CH"), has(i, "CZ"), has(i, "CIA"):
+				out = append(out, 'X')
+				i++
+			case has(i, "CK"):
+				out = append(out, 'K')
+				i++
+			case has(i, "CE"), has(i, "CI"), has(i, "CY"):
+				out = append(out, 'S')
+			default:
+				out = append(out, 'K')
+			}
+		case 'D':
+			if has(i, "DGE") || has(i, "DGI") || has(i, "DGY") {
+				out = append(out, 'J')
+				i++
+			} else {
+				out = append(out, 'T')
+			}
+		case 'G':
+			switch {
+			case has(i, "GH") && i+2 < len(w) && !isVowel(at(i+2)), has(i, "GH") && i+2 == len(w):
+				i++
+			case has(i, "GE"), has(i, "GI"), has(i, "GY"):
+				out = append(out, 'J')
+			default:
+				out = append(out, 'K')
+			}
+		case 'H':
+			if isVowel(at(i+1)) && strings.IndexByte("CGPSTKZ", at(i-1)) < 0 {
+				out = append(out, 'H')
+			}
+		case 'K':
+			if has(i, "KH") {
+				out = append(out, 'H')
+				i++
+			} else if at(i-1) != 'C' {
+				out = append(out, 'K')
+			}
+		case 'P':
+			if has(i, "PH") {
+				out = append(out, 'F')
+				i++
+			} else {
+				out = append(out, 'P')
+			}
+		case 'Q':
+			out = append(out, 'K')
+		case 'S':
+			switch {
+			case has(i, "SCH"), has(i, "SZ"), has(i, "SH"), has(i, "SIO"), has(i, "SIA"):
+				out = append(out, 'X')
+				if has(i, "SCH") {
+					i += 2
+				} else if has(i, "SZ") || has(i, "SH") {
+					i++
+				}
+			default:
+				out = append(out, 'S')
+			}
+		case 'T':
+			switch {
+			case has(i, "TCH"):
+				out = append(out, 'X')
+				i += 2
+			case has(i, "TIA"), has(i, "TIO"):
+				out = append(out, 'X')
+			case has(i, "TH"):
+				out = append(out, '0')
+				i++
+			case has(i, "TS"), has(i, "TZ"):
+				out = append(out, 'S')
+				i++
+			default:
+				out = append(out, 'T')
+			}
+		case 'V', 'W', 'F':
+			// W и V в транслитерациях обозначают один звук, F - его глухую пару
+			if c == 'W' && i == 0 && has(i, "WH") {
+				out = append(out, 'F')
+				i++
+			} else {
+				out = append(out, 'F')
+			}
+		case 'X':
+			out = append(out, 'K', 'S')
+		case 'Y':
+			if i == 0 {
+				out = append(out, 'A')
+			}
+		case 'Z':
+			if has(i, "ZH") {
+				out = append(out, 'J')
+				i++
+			} else {
+				out = append(out, 'S')
+			}
+		case 'J':
+			out = append(out, 'J')
+		default:
+			out = append(out, rune(c))
+		}
+	}
+	return string(collapse(out))
+}
@@ -0,0 +1,59 @@
+// Package phonetic строит фонетические ключи имен, чтобы находить записи,
+// написанные по-разному, но звучащие одинаково (Ivanova/Iwanowa, Шмидт/Шмит).
+// Для кириллицы используется русский Metaphone, для латиницы - вариант Metaphone,
+// учитывающий распространенные транслитерации славянских имен.
+package phonetic
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Key возвращает фонетический ключ строки. Слова кодируются по отдельности
+// и объединяются пробелом; алгоритм выбирается по алфавиту слова.
+func Key(s string) string {
+	var keys []string
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		var key string
+		switch {
+		case isScript(word, unicode.Cyrillic):
+			key = russian(word)
+		case isScript(word, unicode.Latin):
+			key = latin(word)
+		default:
+			key = strings.ToUpper(word)
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return strings.Join(keys, " ")
+}
+
+// Keys возвращает ключи отдельных слов строки
+func Keys(s string) []string {
+	return strings.Fields(Key(s))
+}
+
+func isScript(word string, table *unicode.RangeTable) bool {
+	for _, r := range word {
+		if !unicode.Is(table, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// collapse удаляет подряд идущие повторы символов
+func collapse(s []rune) []rune {
+	out := s[:0]
+	for i, r := range s {
+		if i > 0 && r == s[i-1] {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
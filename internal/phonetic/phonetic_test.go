@@ -0,0 +1,83 @@
+package phonetic
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		// Кириллица: окончания фамилий сжимаются, гласные сводятся к классам,
+		// согласные оглушаются в конце слова и перед глухими
+		{"Иванов", "ИВАН4"},
+		{"Иванова", "ИВАН9"},
+		{"Петров", "ПИТР4"},
+		{"Кузнецов", "КУЗНИЦ4"},
+		{"Знаменский", "ЗНАМИНСК7"},
+		{"Шмидт", "ШМИТ"},
+		{"Юрий", "УР7"},
+		{"Александр", "АЛИКСАНДР"},
+		// Латиница
+		{"Ivanova", "AFNF"},
+		{"Schmidt", "XMT"},
+		{"Zhukov", "JKF"},
+		{"Khabibullin", "HBLN"},
+		{"Chekhov", "XHF"},
+		{"Alexander", "ALKSNTR"},
+		// Слова кодируются по отдельности, символы вне букв разделяют слова
+		{"Иван Петров", "ИВАН ПИТР4"},
+		{"O'Brien", "A BRN"},
+		{"123", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.input); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestKeySameSound(t *testing.T) {
+	groups := [][]string{
+		{"Ivanova", "Iwanowa", "ivanova"},
+		{"Шмидт", "Шмит"},
+		{"Schmidt", "Shmit"},
+		{"Kuznetsov", "Kusnezow"},
+		{"Aleksandr", "Alexander"},
+		{"Dmitriy", "Dmitry"},
+		{"Schukin", "Shchukin"},
+		{"Yuri", "Yuriy"},
+		{"Ёлкин", "Елкин"},
+	}
+	for _, group := range groups {
+		want := Key(group[0])
+		for _, name := range group[1:] {
+			if got := Key(name); got != want {
+				t.Errorf("Key(%q) = %q, want %q as for %q", name, got, want, group[0])
+			}
+		}
+	}
+}
+
+func TestKeyDifferentSound(t *testing.T) {
+	pairs := [][2]string{
+		{"Иванов", "Иванова"},
+		{"Петров", "Сидоров"},
+		{"Ivanov", "Petrov"},
+	}
+	for _, p := range pairs {
+		if Key(p[0]) == Key(p[1]) {
+			t.Errorf("Key(%q) and Key(%q) are both %q", p[0], p[1], Key(p[0]))
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	got := Keys("Иванова  Iwanowa")
+	if want := []string{"ИВАН9", "AFNF"}; !slices.Equal(got, want) {
+		t.Errorf("Keys = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package phonetic
+
+import "strings"
+
+// russianEndings сжимает типичные окончания фамилий в один символ.
+// Порядок важен: длинные окончания проверяются раньше коротких.
+var russianEndings = []struct {
+	suffix string
+	code   string
+}{
+	{"ОВСКИЙ", "@"}, {"ЕВСКИЙ", "#"}, {"ОВСКАЯ", "$"}, {"ЕВСКАЯ", "%"},
+	{"ИЕВА", "9"}, {"ЕЕВА", "9"}, {"ОВА", "9"}, {"ЕВА", "9"},
+	{"ИЕВ", "4"}, {"ЕЕВ", "4"}, {"НКО", "3"}, {"ОВ", "4"}, {"ЕВ", "4"},
+	{"ИНА", "1"}, {"АЯ", "6"}, {"ИЙ", "7"}, {"ЫЙ", "7"},
+	{"ЫХ", "5"}, {"ИХ", "5"}, {"ИН", "8"}, {"ИК", "2"}, {"ЕК", "2"},
+	{"УК", "0"}, {"ЮК", "0"},
+}
+
+var russianVowels = map[rune]rune{
+	'О': 'А', 'Ы': 'А', 'А': 'А', 'Я': 'А',
+	'Ю': 'У', 'У': 'У',
+	'Е': 'И', 'Ё': 'И', 'Э': 'И', 'И': 'И', 'Й': 'И',
+}
+
+// russianDevoice - пары звонких и глухих согласных
+var russianDevoice = map[rune]rune{
+	'Б': 'П', 'В': 'Ф', 'Г': 'К', 'Д': 'Т', 'Ж': 'Ш', 'З': 'С',
+}
+
+var russianVoiceless = map[rune]bool{
+	'П': true, 'Ф': true, 'К': true, 'Т': true, 'Ш': true, 'С': true,
+	'Х': true, 'Ц': true, 'Ч': true, 'Щ': true,
+}
+
+// russian реализует русский Metaphone: сжатие окончаний, сведение гласных
+// к трем классам, оглушение согласных перед глухими и в конце слова,
+// удаление мягкого и твердого знаков и повторов
+func russian(word string) string {
+	word = strings.ToUpper(word)
+	ending := ""
+	for _, e := range russianEndings {
+		if strings.HasSuffix(word, e.suffix) && len([]rune(word)) > len([]rune(e.suffix)) {
+			word = strings.TrimSuffix(word, e.suffix)
+			ending = e.code
+			break
+		}
+	}
+
+	var out []rune
+	for _, r := range word {
+		if r == 'Ь' || r == 'Ъ' {
+			continue
+		}
+		if v, ok := russianVowels[r]; ok {
+			r = v
+		}
+		out = append(out, r)
+	}
+	for i, r := range out {
+		voiceless, ok := russianDevoice[r]
+		if !ok {
+			continue
+		}
+		if i == len(out)-1 && ending == "" || i < len(out)-1 && russianVoiceless[out[i+1]] {
+			out[i] = voiceless
+		}
+	}
+	return string(collapse(out)) + ending
+}
@@ -0,0 +1,32 @@
+// Package tenant описывает арендатора, к которому относятся данные запроса.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default - арендатор записей, созданных до появления арендаторов, и клиентов без привязки к арендатору
+const Default = "default"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Valid сообщает, допустим ли идентификатор арендатора: до 64 строчных латинских букв, цифр, _ и -
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+type tenantKey struct{}
+
+// With сохраняет арендатора в контексте
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext возвращает арендатора из контекста или Default, если он не задан
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantKey{}).(string); ok && id != "" {
+		return id
+	}
+	return Default
+}
@@ -0,0 +1,63 @@
+// Package tracing настраивает трассировку OpenTelemetry: экспорт спанов в коллектор
+// по OTLP/HTTP или в stdout и распространение контекста трассы в формате W3C Trace Context.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Экспортеры спанов
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// serviceName - имя сервиса в трассах, если не задано OTEL_SERVICE_NAME
+const serviceName = "name-analyzer"
+
+// Setup устанавливает глобальный провайдер трассировки с экспортером exporter (none, otlp или stdout).
+// Адрес коллектора OTLP задается стандартными переменными OTEL_EXPORTER_OTLP_*
+// (по умолчанию http://localhost:4318). Возвращаемая функция отправляет накопленные спаны
+// и останавливает провайдер.
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exp, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exp, err = stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exporter: %w", exporter, err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,122 @@
+// Package validate проверяет и нормализует данные о людях, поступающие в API и импорт.
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+const (
+	// maxNameLength соответствует VARCHAR(100) колонок name, surname и patronymic
+	maxNameLength = 100
+	minAge        = 0
+	maxAge        = 150
+)
+
+// genders - допустимые значения пола, совпадают с ответами genderize.io
+var genders = map[string]bool{"male": true, "female": true}
+
+// Коды нарушений в FieldViolation.Code
+const (
+	CodeRequired = "required"
+	CodeTooLong  = "too_long"
+	CodeCharset  = "invalid_characters"
+	CodeRange    = "out_of_range"
+	CodeEnum     = "not_allowed"
+)
+
+// PersonRequest нормализует запрос (обрезает пробелы, приводит пол к нижнему регистру,
+// код страны к верхнему, пустое отчество к null) и возвращает найденные нарушения.
+// При partial пустые имя и фамилия считаются не переданными, как в PUT.
+func PersonRequest(req *model.PersonRequest, partial bool) []model.FieldViolation {
+	var v []model.FieldViolation
+	req.Name = strings.TrimSpace(req.Name)
+	req.Surname = strings.TrimSpace(req.Surname)
+	if req.Name != "" || !partial {
+		v = append(v, Name("name", req.Name, true)...)
+	}
+	if req.Surname != "" || !partial {
+		v = append(v, Name("surname", req.Surname, true)...)
+	}
+	if req.Patronymic != nil {
+		p := strings.TrimSpace(*req.Patronymic)
+		if p == "" {
+			req.Patronymic = nil
+		} else {
+			req.Patronymic = &p
+			v = append(v, Name("patronymic", p, false)...)
+		}
+	}
+	if req.Age != nil && (*req.Age < minAge || *req.Age > maxAge) {
+		v = append(v, model.FieldViolation{
+			Field:   "age",
+			Code:    CodeRange,
+			Message: fmt.Sprintf("must be from %d to %d", minAge, maxAge),
+		})
+	}
+	if req.Gender != nil {
+		g := strings.ToLower(strings.TrimSpace(*req.Gender))
+		req.Gender = &g
+		if !genders[g] {
+			v = append(v, model.FieldViolation{Field: "gender", Code: CodeEnum, Message: "must be male or female"})
+		}
+	}
+	if req.Nationality != nil {
+		n := strings.ToUpper(strings.TrimSpace(*req.Nationality))
+		req.Nationality = &n
+		if !countries[n] {
+			v = append(v, model.FieldViolation{
+				Field:   "nationality",
+				Code:    CodeEnum,
+				Message: "must be an ISO 3166-1 alpha-2 country code",
+			})
+		}
+	}
+	return v
+}
+
+// Name проверяет имя, фамилию или отчество: длину в символах и допустимые символы -
+// буквы любого алфавита, пробелы, дефисы и апострофы; первым должна идти буква
+func Name(field, s string, required bool) []model.FieldViolation {
+	switch {
+	case s == "":
+		if required {
+			return []model.FieldViolation{{Field: field, Code: CodeRequired, Message: "is required"}}
+		}
+		return nil
+	case utf8.RuneCountInString(s) > maxNameLength:
+		return []model.FieldViolation{{
+			Field:   field,
+			Code:    CodeTooLong,
+			Message: fmt.Sprintf("must be at most %d characters", maxNameLength),
+		}}
+	case !validName(s):
+		return []model.FieldViolation{{
+			Field:   field,
+			Code:    CodeCharset,
+			Message: "must contain only letters, spaces, hyphens and apostrophes",
+		}}
+	}
+	return nil
+}
+
+func validName(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+		case i == 0:
+			return false
+		case unicode.Is(unicode.Mn, r), r == ' ', r == '-', r == '\'', r == '’':
+		default:
+			return false
+		}
+	}
+	return true
+}
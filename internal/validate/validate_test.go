@@ -0,0 +1,158 @@
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shenikar/Name-analyzer/internal/model"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// codes возвращает нарушения в виде "поле:код" в порядке проверки
+func codes(violations []model.FieldViolation) []string {
+	var out []string
+	for _, v := range violations {
+		out = append(out, v.Field+":"+v.Code)
+	}
+	return out
+}
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		required bool
+		want     []string
+	}{
+		{"cyrillic", "Иван", true, nil},
+		{"latin", "John", true, nil},
+		{"hyphen and space", "Анна-Мария де ла Вега", true, nil},
+		{"apostrophes", "O'Brien D’Arcy", true, nil},
+		{"empty required", "", true, []string{"name:" + CodeRequired}},
+		{"empty optional", "", false, nil},
+		// 100 двухбайтовых символов - 200 байт, но длина считается в символах
+		{"max runes", strings.Repeat("Я", maxNameLength), true, nil},
+		{"too many runes", strings.Repeat("Я", maxNameLength+1), true, []string{"name:" + CodeTooLong}},
+		{"leading hyphen", "-Иван", true, []string{"name:" + CodeCharset}},
+		{"leading apostrophe", "'Brien", true, []string{"name:" + CodeCharset}},
+		{"leading space", " Иван", true, []string{"name:" + CodeCharset}},
+		{"digits", "Иван2", true, []string{"name:" + CodeCharset}},
+		{"punctuation", "Иван!", true, []string{"name:" + CodeCharset}},
+		// Комбинируемые знаки допустимы после буквы, но не в начале
+		{"combining mark", "Йо\u0308", true, nil},
+		{"leading combining mark", "\u0301Иван", true, []string{"name:" + CodeCharset}},
+		{"invalid utf-8", "Ива\xffн", true, []string{"name:" + CodeCharset}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codes(Name("name", tt.input, tt.required)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Name(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPersonRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     model.PersonRequest
+		partial bool
+		want    model.PersonRequest
+		codes   []string
+	}{
+		{
+			name: "trims and normalizes",
+			req: model.PersonRequest{
+				Name: "  Иван ", Surname: "\tИванов\n", Patronymic: ptr(" Иванович "),
+				Age: ptr(30), Gender: ptr(" Male "), Nationality: ptr(" ru "),
+			},
+			want: model.PersonRequest{
+				Name: "Иван", Surname: "Иванов", Patronymic: ptr("Иванович"),
+				Age: ptr(30), Gender: ptr("male"), Nationality: ptr("RU"),
+			},
+		},
+		{
+			name: "blank patronymic becomes nil",
+			req:  model.PersonRequest{Name: "Иван", Surname: "Иванов", Patronymic: ptr("   ")},
+			want: model.PersonRequest{Name: "Иван", Surname: "Иванов"},
+		},
+		{
+			name:  "full requires name and surname",
+			req:   model.PersonRequest{Name: " ", Surname: ""},
+			want:  model.PersonRequest{},
+			codes: []string{"name:" + CodeRequired, "surname:" + CodeRequired},
+		},
+		{
+			name:    "partial skips empty name and surname",
+			req:     model.PersonRequest{Name: " ", Age: ptr(40)},
+			partial: true,
+			want:    model.PersonRequest{Age: ptr(40)},
+		},
+		{
+			name:    "partial still checks given fields",
+			req:     model.PersonRequest{Surname: "Иванов1", Gender: ptr("other")},
+			partial: true,
+			want:    model.PersonRequest{Surname: "Иванов1", Gender: ptr("other")},
+			codes:   []string{"surname:" + CodeCharset, "gender:" + CodeEnum},
+		},
+		{
+			name:  "invalid patronymic",
+			req:   model.PersonRequest{Name: "Иван", Surname: "Иванов", Patronymic: ptr("-Иванович")},
+			want:  model.PersonRequest{Name: "Иван", Surname: "Иванов", Patronymic: ptr("-Иванович")},
+			codes: []string{"patronymic:" + CodeCharset},
+		},
+		{
+			name: "age lower bound",
+			req:  model.PersonRequest{Name: "Иван", Surname: "Иванов", Age: ptr(minAge)},
+			want: model.PersonRequest{Name: "Иван", Surname: "Иванов", Age: ptr(minAge)},
+		},
+		{
+			name: "age upper bound",
+			req:  model.PersonRequest{Name: "Иван", Surname: "Иванов", Age: ptr(maxAge)},
+			want: model.PersonRequest{Name: "Иван", Surname: "Иванов", Age: ptr(maxAge)},
+		},
+		{
+			name:  "negative age",
+			req:   model.PersonRequest{Name: "Иван", Surname: "Иванов", Age: ptr(minAge - 1)},
+			want:  model.PersonRequest{Name: "Иван", Surname: "Иванов", Age: ptr(minAge - 1)},
+			codes: []string{"age:" + CodeRange},
+		},
+		{
+			name:  "age above maximum",
+			req:   model.PersonRequest{Name: "Иван", Surname: "Иванов", Age: ptr(maxAge + 1)},
+			want:  model.PersonRequest{Name: "Иван", Surname: "Иванов", Age: ptr(maxAge + 1)},
+			codes: []string{"age:" + CodeRange},
+		},
+		{
+			name: "kosovo",
+			req:  model.PersonRequest{Name: "Иван", Surname: "Иванов", Nationality: ptr("xk")},
+			want: model.PersonRequest{Name: "Иван", Surname: "Иванов", Nationality: ptr("XK")},
+		},
+		{
+			name:  "unknown country",
+			req:   model.PersonRequest{Name: "Иван", Surname: "Иванов", Nationality: ptr("rus")},
+			want:  model.PersonRequest{Name: "Иван", Surname: "Иванов", Nationality: ptr("RUS")},
+			codes: []string{"nationality:" + CodeEnum},
+		},
+		{
+			name:  "empty gender",
+			req:   model.PersonRequest{Name: "Иван", Surname: "Иванов", Gender: ptr(" ")},
+			want:  model.PersonRequest{Name: "Иван", Surname: "Иванов", Gender: ptr("")},
+			codes: []string{"gender:" + CodeEnum},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			got := codes(PersonRequest(&req, tt.partial))
+			if !reflect.DeepEqual(got, tt.codes) {
+				t.Errorf("violations = %v, want %v", got, tt.codes)
+			}
+			if !reflect.DeepEqual(req, tt.want) {
+				t.Errorf("normalized request = %+v, want %+v", req, tt.want)
+			}
+		})
+	}
+}
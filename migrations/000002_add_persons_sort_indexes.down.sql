@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_persons_nationality_id;
+
+DROP INDEX IF EXISTS idx_persons_gender_id;
+
+DROP INDEX IF EXISTS idx_persons_age_id;
+
+DROP INDEX IF EXISTS idx_persons_surname_id;
+
+DROP INDEX IF EXISTS idx_persons_name_id;
+
+DROP INDEX IF EXISTS idx_persons_updated_at_id;
+
+DROP INDEX IF EXISTS idx_persons_created_at_id;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE INDEX IF NOT EXISTS idx_persons_created_at_id ON persons (created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_persons_updated_at_id ON persons (updated_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_persons_name_id ON persons (name, id);
+
+CREATE INDEX IF NOT EXISTS idx_persons_surname_id ON persons (surname, id);
+
+CREATE INDEX IF NOT EXISTS idx_persons_age_id ON persons (age, id);
+
+CREATE INDEX IF NOT EXISTS idx_persons_gender_id ON persons (gender, id);
+
+CREATE INDEX IF NOT EXISTS idx_persons_nationality_id ON persons (nationality, id);
\ No newline at end of file
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_persons_surname_phonetic;
+
+DROP INDEX IF EXISTS idx_persons_name_phonetic;
+
+DROP INDEX IF EXISTS idx_persons_surname_trgm;
+
+DROP INDEX IF EXISTS idx_persons_name_trgm;
+
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS surname_phonetic,
+    DROP COLUMN IF EXISTS name_phonetic;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS name_phonetic VARCHAR(200),
+    ADD COLUMN IF NOT EXISTS surname_phonetic VARCHAR(200);
+
+CREATE INDEX IF NOT EXISTS idx_persons_name_trgm ON persons USING GIN (name gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_persons_surname_trgm ON persons USING GIN (surname gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_persons_name_phonetic ON persons (name_phonetic);
+
+CREATE INDEX IF NOT EXISTS idx_persons_surname_phonetic ON persons (surname_phonetic);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS person_merges;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE
+    IF NOT EXISTS person_merges (
+        id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+        target_id UUID NOT NULL,
+        source_id UUID NOT NULL,
+        target_before JSONB NOT NULL,
+        source_snapshot JSONB NOT NULL,
+        merged_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE INDEX IF NOT EXISTS idx_person_merges_target_id ON person_merges (target_id);
+
+CREATE INDEX IF NOT EXISTS idx_person_merges_source_id ON person_merges (source_id);
\ No newline at end of file
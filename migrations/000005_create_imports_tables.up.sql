@@ -0,0 +1,23 @@
+CREATE TABLE
+    IF NOT EXISTS imports (
+        id UUID PRIMARY KEY DEFAULT uuid_generate_v4 (),
+        format VARCHAR(10) NOT NULL,
+        status VARCHAR(20) NOT NULL DEFAULT 'pending',
+        mapping JSONB NOT NULL,
+        total_rows INT NOT NULL DEFAULT 0,
+        imported_rows INT NOT NULL DEFAULT 0,
+        failed_rows INT NOT NULL DEFAULT 0,
+        error TEXT,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+        finished_at TIMESTAMPTZ
+    );
+
+CREATE TABLE
+    IF NOT EXISTS import_errors (
+        import_id UUID NOT NULL REFERENCES imports (id) ON DELETE CASCADE,
+        row_number INT NOT NULL,
+        raw TEXT NOT NULL,
+        error TEXT NOT NULL,
+        PRIMARY KEY (import_id, row_number)
+    );
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS enrichment_outcomes;
+
+DROP TABLE IF EXISTS person_events;
\ No newline at end of file
@@ -0,0 +1,27 @@
+CREATE TABLE
+    IF NOT EXISTS person_events (
+        id BIGSERIAL PRIMARY KEY,
+        person_id UUID NOT NULL,
+        kind VARCHAR(20) NOT NULL,
+        occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE INDEX IF NOT EXISTS idx_person_events_occurred_at ON person_events (occurred_at);
+
+INSERT INTO person_events (person_id, kind, occurred_at)
+SELECT id, 'created', created_at FROM persons;
+
+INSERT INTO person_events (person_id, kind, occurred_at)
+SELECT id, 'updated', updated_at FROM persons WHERE updated_at > created_at;
+
+CREATE TABLE
+    IF NOT EXISTS enrichment_outcomes (
+        id BIGSERIAL PRIMARY KEY,
+        provider VARCHAR(20) NOT NULL,
+        success BOOLEAN NOT NULL,
+        error TEXT,
+        duration_ms INT NOT NULL,
+        occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE INDEX IF NOT EXISTS idx_enrichment_outcomes_occurred_at ON enrichment_outcomes (occurred_at);
\ No newline at end of file
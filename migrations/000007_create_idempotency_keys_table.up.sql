@@ -0,0 +1,14 @@
+CREATE TABLE
+    IF NOT EXISTS idempotency_keys (
+        key VARCHAR(255) NOT NULL,
+        scope CHAR(64) NOT NULL,
+        request_hash CHAR(64) NOT NULL,
+        status INT,
+        header JSONB,
+        body BYTEA,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+        expires_at TIMESTAMPTZ NOT NULL,
+        PRIMARY KEY (key, scope)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
\ No newline at end of file
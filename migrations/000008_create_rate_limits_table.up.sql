@@ -0,0 +1,9 @@
+CREATE TABLE
+    IF NOT EXISTS rate_limits (
+        key VARCHAR(255) PRIMARY KEY,
+        tokens DOUBLE PRECISION NOT NULL,
+        allowed BOOLEAN NOT NULL,
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+    );
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_updated_at ON rate_limits (updated_at);
\ No newline at end of file
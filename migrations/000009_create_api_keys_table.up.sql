@@ -0,0 +1,11 @@
+CREATE TABLE
+    IF NOT EXISTS api_keys (
+        id UUID PRIMARY KEY,
+        name VARCHAR(100) NOT NULL,
+        prefix VARCHAR(16) NOT NULL,
+        key_hash CHAR(64) NOT NULL UNIQUE,
+        scopes TEXT NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+        last_used_at TIMESTAMPTZ,
+        revoked_at TIMESTAMPTZ
+    );
\ No newline at end of file
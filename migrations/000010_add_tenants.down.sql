@@ -0,0 +1,81 @@
+ALTER TABLE persons DISABLE ROW LEVEL SECURITY, NO FORCE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON persons;
+
+ALTER TABLE imports DISABLE ROW LEVEL SECURITY, NO FORCE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON imports;
+
+ALTER TABLE person_merges DISABLE ROW LEVEL SECURITY, NO FORCE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON person_merges;
+
+ALTER TABLE person_events DISABLE ROW LEVEL SECURITY, NO FORCE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON person_events;
+
+ALTER TABLE enrichment_outcomes DISABLE ROW LEVEL SECURITY, NO FORCE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON enrichment_outcomes;
+
+ALTER TABLE import_errors DISABLE ROW LEVEL SECURITY, NO FORCE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON import_errors;
+
+DROP TABLE IF EXISTS enrichment_usage;
+
+DROP TABLE IF EXISTS tenant_quotas;
+
+DROP INDEX IF EXISTS idx_enrichment_outcomes_tenant_occurred_at;
+
+CREATE INDEX IF NOT EXISTS idx_enrichment_outcomes_occurred_at ON enrichment_outcomes (occurred_at);
+
+DROP INDEX IF EXISTS idx_person_events_tenant_occurred_at;
+
+CREATE INDEX IF NOT EXISTS idx_person_events_occurred_at ON person_events (occurred_at);
+
+DROP INDEX IF EXISTS idx_persons_tenant_created_at_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_created_at_id ON persons (created_at, id);
+
+DROP INDEX IF EXISTS idx_persons_tenant_updated_at_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_updated_at_id ON persons (updated_at, id);
+
+DROP INDEX IF EXISTS idx_persons_tenant_name_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_name_id ON persons (name, id);
+
+DROP INDEX IF EXISTS idx_persons_tenant_surname_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_surname_id ON persons (surname, id);
+
+DROP INDEX IF EXISTS idx_persons_tenant_age_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_age_id ON persons (age, id);
+
+DROP INDEX IF EXISTS idx_persons_tenant_gender_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_gender_id ON persons (gender, id);
+
+DROP INDEX IF EXISTS idx_persons_tenant_nationality_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_nationality_id ON persons (nationality, id);
+
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE imports
+    DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE person_merges
+    DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE person_events
+    DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE enrichment_outcomes
+    DROP COLUMN IF EXISTS tenant_id;
\ No newline at end of file
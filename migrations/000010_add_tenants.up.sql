@@ -0,0 +1,115 @@
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+ALTER TABLE persons
+    ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE imports
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+ALTER TABLE imports
+    ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE person_merges
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+ALTER TABLE person_merges
+    ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE person_events
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+ALTER TABLE person_events
+    ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE enrichment_outcomes
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+ALTER TABLE enrichment_outcomes
+    ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64);
+
+DROP INDEX IF EXISTS idx_persons_created_at_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_tenant_created_at_id ON persons (tenant_id, created_at, id);
+
+DROP INDEX IF EXISTS idx_persons_updated_at_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_tenant_updated_at_id ON persons (tenant_id, updated_at, id);
+
+DROP INDEX IF EXISTS idx_persons_name_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_tenant_name_id ON persons (tenant_id, name, id);
+
+DROP INDEX IF EXISTS idx_persons_surname_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_tenant_surname_id ON persons (tenant_id, surname, id);
+
+DROP INDEX IF EXISTS idx_persons_age_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_tenant_age_id ON persons (tenant_id, age, id);
+
+DROP INDEX IF EXISTS idx_persons_gender_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_tenant_gender_id ON persons (tenant_id, gender, id);
+
+DROP INDEX IF EXISTS idx_persons_nationality_id;
+
+CREATE INDEX IF NOT EXISTS idx_persons_tenant_nationality_id ON persons (tenant_id, nationality, id);
+
+DROP INDEX IF EXISTS idx_person_events_occurred_at;
+
+CREATE INDEX IF NOT EXISTS idx_person_events_tenant_occurred_at ON person_events (tenant_id, occurred_at);
+
+DROP INDEX IF EXISTS idx_enrichment_outcomes_occurred_at;
+
+CREATE INDEX IF NOT EXISTS idx_enrichment_outcomes_tenant_occurred_at ON enrichment_outcomes (tenant_id, occurred_at);
+
+CREATE TABLE
+    IF NOT EXISTS tenant_quotas (
+        tenant_id VARCHAR(64) PRIMARY KEY,
+        daily_enrichments INT
+    );
+
+CREATE TABLE
+    IF NOT EXISTS enrichment_usage (
+        tenant_id VARCHAR(64) NOT NULL,
+        day DATE NOT NULL,
+        used INT NOT NULL,
+        PRIMARY KEY (tenant_id, day)
+    );
+
+CREATE POLICY tenant_isolation ON persons
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on');
+
+CREATE POLICY tenant_isolation ON imports
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on');
+
+CREATE POLICY tenant_isolation ON person_merges
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on');
+
+CREATE POLICY tenant_isolation ON person_events
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on');
+
+CREATE POLICY tenant_isolation ON enrichment_outcomes
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.maintenance', true) = 'on');
+
+CREATE POLICY tenant_isolation ON import_errors
+    USING (EXISTS (SELECT 1 FROM imports WHERE imports.id = import_errors.import_id));
\ No newline at end of file
@@ -0,0 +1,10 @@
+// Package migrations встраивает SQL-миграции в бинарный файл сервиса,
+// чтобы они не зависели от рабочего каталога процесса
+package migrations
+
+import "embed"
+
+// FS содержит файлы миграций в формате golang-migrate: NNNNNN_name.up.sql и NNNNNN_name.down.sql
+//
+//go:embed *.sql
+var FS embed.FS